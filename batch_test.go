@@ -0,0 +1,87 @@
+package lex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStringLexer_Batched(t *testing.T) {
+	for _, size := range []int{2, 3, 100} {
+		tlc := &testLexCtx{}
+		l := NewStringLexer("1 +\n 2", tlc.lexStart)
+		l.SetBatchSize(size)
+		go l.Run()
+
+		verifyItems(t, testItems, collect(l))
+	}
+}
+
+func TestReaderLexer_Batched(t *testing.T) {
+	for _, size := range []int{2, 3, 100} {
+		tlc := &testLexCtx{}
+		l := NewReaderLexer(bytes.NewBufferString("1 +\n 2"), tlc.lexStart)
+		l.SetBatchSize(size)
+		go l.Run()
+
+		verifyItems(t, testItems, collect(l))
+	}
+}
+
+func TestStringLexer_BatchedError(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer("1 x", tlc.lexStart)
+	l.SetBatchSize(10)
+	go l.Run()
+
+	batch, ok := <-l.Batches()
+	if !ok {
+		t.Fatalf("expected a batch")
+	}
+	if len(batch) != 3 {
+		t.Fatalf("expected 3 items in batch, got %d", len(batch))
+	}
+	if batch[2].Type() != ItemError {
+		t.Errorf("expected last item to be an error, got %s", batch[2].Type())
+	}
+	if _, ok := <-l.Batches(); ok {
+		t.Errorf("expected batches channel to be closed")
+	}
+}
+
+var benchInput = strings.Repeat("1 + 23 + 456\n", 1000)
+
+func BenchmarkStringLexer_Items(b *testing.B) {
+	tlc := &testLexCtx{}
+	for i := 0; i < b.N; i++ {
+		l := NewStringLexer(benchInput, tlc.lexStart)
+		go l.Run()
+		for range l.Items() {
+		}
+	}
+}
+
+func BenchmarkStringLexer_Batched(b *testing.B) {
+	tlc := &testLexCtx{}
+	for i := 0; i < b.N; i++ {
+		l := NewStringLexer(benchInput, tlc.lexStart)
+		l.SetBatchSize(128)
+		go l.Run()
+		for item := l.NextItem(); item != nil; item = l.NextItem() {
+		}
+	}
+}
+
+// benchLongLine has as many tokens as benchInput, but on a single line
+var benchLongLine = strings.Repeat("1 + 23 + 456 ", 1000)
+
+func BenchmarkStringLexer_LongLine(b *testing.B) {
+	tlc := &testLexCtx{}
+	for i := 0; i < b.N; i++ {
+		l := NewStringLexer(benchLongLine, tlc.lexStart)
+		l.SetBatchSize(128)
+		go l.Run()
+		for item := l.NextItem(); item != nil; item = l.NextItem() {
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package lex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// The binary format starts with a 4 byte magic and a version byte,
+// followed by a sequence of records, and a CRC32 (IEEE) checksum of the
+// records in big endian order. Each record starts with a marker byte:
+// recordEnd terminates the sequence, and recordItem is followed by
+//
+//	varint  type
+//	varint  position, relative to the previous item
+//	varint  line, relative to the previous item
+//	uvarint column
+//	uvarint value: 0 for a new string, followed by its uvarint length and
+//	        bytes, or n to reuse the n-th string seen so far
+//
+// Payloads are not encoded
+const (
+	binaryMagic   = "LEXB"
+	binaryVersion = 1
+
+	recordEnd  = 0
+	recordItem = 1
+
+	maxBinaryString   = 1 << 30
+	smallBinaryString = 64 << 10
+)
+
+var (
+	// ErrBadMagic is returned by ReadBinary if the input is not in the
+	// binary item format
+	ErrBadMagic = errors.New("not a binary item stream")
+	// ErrChecksum is returned by ReadBinary if the checksum of the
+	// input does not match its contents
+	ErrChecksum = errors.New("binary item stream checksum mismatch")
+)
+
+type binaryWriter struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (bw *binaryWriter) write(b []byte) {
+	bw.w.Write(b)
+	bw.crc.Write(b)
+}
+
+func (bw *binaryWriter) writeByte(b byte) {
+	bw.write([]byte{b})
+}
+
+func (bw *binaryWriter) writeVarint(v int64) {
+	bw.write(bw.buf[:binary.PutVarint(bw.buf[:], v)])
+}
+
+func (bw *binaryWriter) writeUvarint(v uint64) {
+	bw.write(bw.buf[:binary.PutUvarint(bw.buf[:], v)])
+}
+
+// WriteBinary writes the items from `src` to `w` in a compact binary
+// format, until `src` is exhausted. If `src` is a Lexer, it must be
+// running. Use ReadBinary to read them back
+func WriteBinary(w io.Writer, src ItemSource) error {
+	bw := &binaryWriter{w: bufio.NewWriter(w), crc: crc32.NewIEEE()}
+	bw.w.WriteString(binaryMagic)
+	bw.w.WriteByte(binaryVersion)
+
+	table := make(map[string]uint64)
+	var pos, line int
+	for item := src.NextItem(); item != nil; item = src.NextItem() {
+		bw.writeByte(recordItem)
+		bw.writeVarint(int64(item.Type()))
+		bw.writeVarint(int64(item.Pos() - pos))
+		bw.writeVarint(int64(item.Line() - line))
+		bw.writeUvarint(uint64(item.Column()))
+		pos, line = item.Pos(), item.Line()
+
+		if ref, ok := table[item.Value()]; ok {
+			bw.writeUvarint(ref)
+			continue
+		}
+		table[item.Value()] = uint64(len(table) + 1)
+		bw.writeUvarint(0)
+		bw.writeUvarint(uint64(len(item.Value())))
+		bw.write([]byte(item.Value()))
+	}
+	bw.writeByte(recordEnd)
+
+	binary.BigEndian.PutUint32(bw.buf[:4], bw.crc.Sum32())
+	bw.w.Write(bw.buf[:4])
+	return bw.w.Flush()
+}
+
+type binaryReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (br *binaryReader) ReadByte() (byte, error) {
+	b, err := br.r.ReadByte()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	br.crc.Write([]byte{b})
+	return b, nil
+}
+
+// readString reads a string of `n` bytes. The length comes from input
+// that has not been verified yet, so large strings are read into a
+// buffer that grows as the bytes arrive, instead of being allocated
+// upfront
+func (br *binaryReader) readString(n uint64) (string, error) {
+	if n <= smallBinaryString {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(br.r, b); err != nil {
+			return "", unexpectedEOF(err)
+		}
+		br.crc.Write(b)
+		return string(b), nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(io.MultiWriter(&buf, br.crc), br.r, int64(n)); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return buf.String(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ReadBinary reads the items written by WriteBinary. The whole stream is
+// read and its checksum verified before the items are returned
+func ReadBinary(r io.Reader) ([]Item, error) {
+	br := &binaryReader{r: bufio.NewReader(r), crc: crc32.NewIEEE()}
+
+	header := make([]byte, len(binaryMagic)+1)
+	if _, err := io.ReadFull(br.r, header); err != nil || string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, ErrBadMagic
+	}
+	if v := header[len(binaryMagic)]; v != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary item stream version %d", v)
+	}
+
+	var items []Item
+	var table []string
+	var pos, line int
+	for {
+		marker, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if marker == recordEnd {
+			break
+		}
+		if marker != recordItem {
+			return nil, fmt.Errorf("invalid record marker %d", marker)
+		}
+
+		var fields [3]int64
+		for i := range fields {
+			if fields[i], err = binary.ReadVarint(br); err != nil {
+				return nil, err
+			}
+		}
+		col, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		pos += int(fields[1])
+		line += int(fields[2])
+
+		ref, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+
+		var val string
+		switch {
+		case ref == 0:
+			n, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, err
+			}
+			if n > maxBinaryString {
+				return nil, fmt.Errorf("string of %d bytes is too long", n)
+			}
+			if val, err = br.readString(n); err != nil {
+				return nil, err
+			}
+			table = append(table, val)
+		case ref <= uint64(len(table)):
+			val = table[ref-1]
+		default:
+			return nil, fmt.Errorf("invalid string reference %d", ref)
+		}
+
+		items = append(items, Item{typ: ItemType(fields[0]), pos: pos, line: line, col: int(col), val: val})
+	}
+
+	sum := make([]byte, 4)
+	if _, err := io.ReadFull(br.r, sum); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if binary.BigEndian.Uint32(sum) != br.crc.Sum32() {
+		return nil, ErrChecksum
+	}
+	return items, nil
+}
+
+// ReplayLexer hands out a fixed sequence of items, such as the ones read
+// by ReadBinary or ReadItems, through the same NextItem and Items
+// interface as the other lexers, so that the consumers can't tell the
+// difference:
+//
+//	l := lex.NewReplayLexer(items)
+//	go l.Run()
+//	for item := l.NextItem(); item != nil; item = l.NextItem() {
+//	  ...
+//	}
+type ReplayLexer struct {
+	items []Item
+	out   output
+}
+
+// NewReplayLexer creates a ReplayLexer handing out `items`. The options
+// that affect the delivery of the items, such as WithBufferSize and
+// WithBatchSize, are honored
+func NewReplayLexer(items []Item, options ...Option) *ReplayLexer {
+	return &ReplayLexer{
+		items: items,
+		out:   newOutput(newConfig(options)),
+	}
+}
+
+// Run sends the items to the consumer. You should be calling this method
+// as a goroutine
+func (l *ReplayLexer) Run() {
+	for _, item := range l.items {
+		if l.out.aborted() {
+			break
+		}
+		l.out.send(item)
+	}
+	l.out.close()
+}
+
+// Items returns the channel where the items are sent to. If batching is
+// enabled, use NextItem() or Batches() instead
+func (l *ReplayLexer) Items() chan LexItem {
+	return l.out.items
+}
+
+// Batches returns the channel where batches of items are sent to. It is
+// nil unless batching has been enabled via WithBatchSize
+func (l *ReplayLexer) Batches() chan []LexItem {
+	return l.out.batches
+}
+
+// Err returns the error returned by the item sink specified via
+// WithItemSink, if any
+func (l *ReplayLexer) Err() error {
+	return l.out.err
+}
+
+// NextItem returns the next item, or nil once all of the items have been
+// handed out
+func (l *ReplayLexer) NextItem() LexItem {
+	return l.out.next()
+}
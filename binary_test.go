@@ -0,0 +1,91 @@
+package lex
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	const input = "1 + 23  +\n 4 + 23"
+	tlc := &testLexCtx{}
+	l := NewStringLexer(input, tlc.lexStart)
+	go l.Run()
+
+	var expected []LexItem
+	var buf bytes.Buffer
+	err := WriteBinary(&buf, ItemSourceFunc(func() LexItem {
+		item := l.NextItem()
+		if item != nil {
+			expected = append(expected, item)
+		}
+		return item
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	items, err := ReadBinary(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, got %d", len(expected), len(items))
+	}
+	for i, item := range items {
+		if item != expected[i] {
+			t.Errorf("item %d: expected %#v, got %#v", i, expected[i], item)
+		}
+	}
+
+	for _, options := range [][]Option{nil, {WithBatchSize(4)}} {
+		r := NewReplayLexer(items, options...)
+		go r.Run()
+		if got := collect(r); len(got) != len(expected) || got[len(got)-1] != expected[len(expected)-1] {
+			t.Errorf("replay with %d options: expected %d items, got %d", len(options), len(expected), len(got))
+		}
+	}
+}
+
+func TestBinary_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	items := []Item{NewItem(ItemNewline, 0, 1, "\n"), NewItem(ItemEOF, 1, 2, "")}
+	r := NewReplayLexer(items)
+	go r.Run()
+	if err := WriteBinary(&buf, r); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	valid := buf.Bytes()
+
+	// Replace the value of the newline, which doesn't change the structure
+	corrupt := append([]byte{}, valid...)
+	corrupt[bytes.IndexByte(corrupt[5:], '\n')+5] = '\r'
+
+	tests := map[string]struct {
+		input    []byte
+		expected error
+	}{
+		"bad magic":    {[]byte("JSON{}"), ErrBadMagic},
+		"truncated":    {valid[:len(valid)-2], io.ErrUnexpectedEOF},
+		"no checksum":  {valid[:len(valid)-4], io.ErrUnexpectedEOF},
+		"bad checksum": {corrupt, ErrChecksum},
+	}
+	for name, test := range tests {
+		if _, err := ReadBinary(bytes.NewReader(test.input)); err != test.expected {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, err)
+		}
+	}
+
+	// A string claiming to be 1 GiB long, but cut short
+	huge := []byte(binaryMagic + "\x01\x01\x00\x00\x00\x00\x00\x80\x80\x80\x80\x04abc")
+	if _, err := ReadBinary(bytes.NewReader(huge)); err != io.ErrUnexpectedEOF {
+		t.Errorf("huge string: expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	version := append([]byte{}, valid...)
+	version[4] = 99
+	if _, err := ReadBinary(bytes.NewReader(version)); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const testSource = `package calc
+
+import "github.com/lestrrat-go/lex"
+
+const (
+	ItemNumber lex.ItemType = lex.ItemDefaultMax + 1 + iota
+	ItemOperator
+	_
+	ItemWhitespace
+	maxSize = 10
+	notAnItem
+)
+
+const ItemKeyword = lex.ItemDefaultMax + 100
+
+const (
+	unrelated = iota
+	alsoUnrelated
+)
+`
+
+func parseSource(t *testing.T, src string) []*ast.File {
+	f, err := parser.ParseFile(token.NewFileSet(), "calc.go", src, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return []*ast.File{f}
+}
+
+func TestFindItemTypes(t *testing.T) {
+	consts := findItemTypes(parseSource(t, testSource))
+	expected := "ItemNumber ItemOperator ItemWhitespace ItemKeyword"
+	if strings.Join(consts, " ") != expected {
+		t.Errorf("expected %s, got %v", expected, consts)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("calc", parseSource(t, testSource), "Item", "Values")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{
+		"// Code generated by itemgen. DO NOT EDIT.",
+		`import "github.com/lestrrat-go/lex"`,
+		`lex.TypeNames[ItemNumber] = "Number"`,
+		`lex.TypeNames[ItemKeyword] = "Keyword"`,
+		"func Values() []lex.ItemType {",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("expected generated source to contain %q", expected)
+		}
+	}
+
+	if _, err := generate("calc", parseSource(t, "package calc\nconst x = 1\n"), "Item", "Values"); err == nil {
+		t.Errorf("expected an error when there are no ItemTypes")
+	}
+}
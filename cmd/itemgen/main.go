@@ -0,0 +1,192 @@
+/*
+itemgen generates the boilerplate for ItemTypes. It reads the Go source
+files in a directory, and looks for const blocks that define ItemTypes
+relative to lex.ItemDefaultMax:
+
+	const (
+	  ItemNumber lex.ItemType = lex.ItemDefaultMax + 1 + iota
+	  ItemOperator
+	)
+
+For each of them, the generated source registers a name in lex.TypeNames
+(the constant's name without the "Item" prefix, e.g. "Number"), and a
+function listing all of them is defined. Once registered, the names are
+used by ItemType's String, MarshalText and UnmarshalText methods and by
+lex.ParseItemType. It is meant to be used with go generate:
+
+	//go:generate itemgen -o itemtypes_gen.go
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	if err := _main(); err != nil {
+		fmt.Fprintf(os.Stderr, "itemgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func _main() error {
+	var output, trimPrefix, funcName string
+	flag.StringVar(&output, "o", "itemtypes_gen.go", "output file, relative to the directory")
+	flag.StringVar(&trimPrefix, "trimprefix", "Item", "prefix to remove from the constant names")
+	flag.StringVar(&funcName, "func", "Values", "name of the function listing the ItemTypes")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: itemgen [flags] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != filepath.Base(output)
+	}, 0)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("expected 1 package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	var files []*ast.File
+	var names []string
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		files = append(files, pkg.Files[name])
+	}
+
+	src, err := generate(pkg.Name, files, trimPrefix, funcName)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, output), src, 0644)
+}
+
+// findItemTypes returns the names of the constants defined relative to
+// ItemDefaultMax, in the order they appear in `files`
+func findItemTypes(files []*ast.File) []string {
+	var consts []string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+
+			// Specs without values repeat the previous expression, so
+			// they belong to the same enumeration
+			inEnum := false
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if len(vs.Values) > 0 {
+					inEnum = len(vs.Values) == 1 && refersToDefaultMax(vs.Values[0])
+				}
+				if !inEnum {
+					continue
+				}
+				for _, name := range vs.Names {
+					if name.Name != "_" {
+						consts = append(consts, name.Name)
+					}
+				}
+			}
+		}
+	}
+	return consts
+}
+
+func refersToDefaultMax(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && x.Name == "lex" && n.Sel.Name == "ItemDefaultMax" {
+				found = true
+			}
+			return false
+		case *ast.Ident:
+			if n.Name == "ItemDefaultMax" {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// generate creates the source registering the ItemTypes found in `files`
+func generate(pkgName string, files []*ast.File, trimPrefix, funcName string) ([]byte, error) {
+	consts := findItemTypes(files)
+	if len(consts) == 0 {
+		return nil, fmt.Errorf("no ItemType constants based on ItemDefaultMax found")
+	}
+
+	// Within the lex package itself, the identifiers are not qualified
+	qualifier := "lex."
+	if pkgName == "lex" {
+		qualifier = ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by itemgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	if qualifier != "" {
+		fmt.Fprintf(&buf, "import \"github.com/lestrrat-go/lex\"\n\n")
+	}
+
+	fmt.Fprintf(&buf, "func init() {\n")
+	for _, name := range consts {
+		typeName := strings.TrimPrefix(name, trimPrefix)
+		if typeName == "" {
+			typeName = name
+		}
+		fmt.Fprintf(&buf, "%sTypeNames[%s] = %q\n", qualifier, name, typeName)
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+
+	fmt.Fprintf(&buf, "// %s returns the ItemTypes defined in this package\n", funcName)
+	fmt.Fprintf(&buf, "func %s() []%sItemType {\n", funcName, qualifier)
+	fmt.Fprintf(&buf, "return []%sItemType{\n", qualifier)
+	for _, name := range consts {
+		fmt.Fprintf(&buf, "%s,\n", name)
+	}
+	fmt.Fprintf(&buf, "}\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %s", err)
+	}
+	return src, nil
+}
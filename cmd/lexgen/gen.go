@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type generator struct {
+	buf     bytes.Buffer
+	spec    *spec
+	classes int
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate creates the Go source for `s`. `source` is the name of the
+// spec file, which is mentioned in the header
+func generate(s *spec, source string) ([]byte, error) {
+	g := &generator{spec: s}
+	g.printf("// Code generated by lexgen from %s. DO NOT EDIT.\n\n", source)
+	g.printf("package %s\n\n", s.pkg)
+	g.printf("import \"github.com/lestrrat-go/lex\"\n\n")
+
+	g.genTypes()
+	for _, m := range s.modes {
+		g.genMode(m)
+	}
+	for _, m := range s.modes {
+		for _, rl := range m.rules {
+			g.genMatcher(rl)
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %s", err)
+	}
+	return src, nil
+}
+
+func (g *generator) typeName(rl *rule) string {
+	return g.spec.prefix + rl.name
+}
+
+func (g *generator) lexFnName(m *mode) string {
+	r, n := utf8.DecodeRuneInString(m.name)
+	return "lex" + string(unicode.ToUpper(r)) + m.name[n:]
+}
+
+func (g *generator) genTypes() {
+	var tokens []*rule
+	for _, m := range g.spec.modes {
+		for _, rl := range m.rules {
+			if !rl.skip {
+				tokens = append(tokens, rl)
+			}
+		}
+	}
+	if len(tokens) == 0 {
+		return
+	}
+
+	g.printf("const (\n")
+	for i, rl := range tokens {
+		if i == 0 {
+			g.printf("%s lex.ItemType = lex.ItemDefaultMax + 1 + iota\n", g.typeName(rl))
+			continue
+		}
+		g.printf("%s\n", g.typeName(rl))
+	}
+	g.printf(")\n\n")
+
+	g.printf("func init() {\n")
+	for _, rl := range tokens {
+		g.printf("lex.TypeNames[%s] = %q\n", g.typeName(rl), rl.name)
+	}
+	g.printf("}\n\n")
+}
+
+// genMode generates the LexFn for a mode. Each rule is tried in turn, and
+// the longest match wins. If several rules match the same number of
+// runes, the one defined first wins
+func (g *generator) genMode(m *mode) {
+	fn := g.lexFnName(m)
+	g.printf("// %s lexes the %s mode\n", fn, m.name)
+	g.printf("func %s(l lex.Lexer) lex.LexFn {\n", fn)
+	g.printf("if l.Peek() == lex.EOF {\nl.Emit(lex.ItemEOF)\nreturn nil\n}\n\n")
+
+	g.printf("best, longest := -1, 0\n")
+	for i, rl := range m.rules {
+		g.printf("if n, ok := match%s(l); ok && n > longest {\nbest, longest = %d, n\n}\n", rl.name, i)
+	}
+	g.printf("for i := 0; i < longest; i++ {\nl.Next()\n}\n\n")
+
+	g.printf("switch best {\n")
+	for i, rl := range m.rules {
+		g.printf("case %d:\n", i)
+		if rl.skip {
+			g.printf("l.Ignore()\n")
+		} else {
+			g.printf("l.Emit(%s)\n", g.typeName(rl))
+		}
+
+		next := m
+		if rl.next != "" {
+			next = g.spec.mode(rl.next)
+		}
+		g.printf("return %s\n", g.lexFnName(next))
+	}
+	g.printf("}\n")
+	g.printf("return l.EmitErrorf(\"unexpected character %%q\", l.Peek())\n")
+	g.printf("}\n\n")
+}
+
+// genMatcher generates a function which reports the number of runes
+// matched by the rule's pattern, and then moves the cursor back to where
+// it started. Quantifiers are greedy, and never give back what they
+// matched
+func (g *generator) genMatcher(rl *rule) {
+	var classes bytes.Buffer
+
+	g.printf("func match%s(l lex.Lexer) (n int, ok bool) {\n", rl.name)
+	g.printf("defer func() {\nfor i := 0; i < n; i++ {\nl.Backup()\n}\n}()\n\n")
+	for _, a := range rl.atoms {
+		if a.class != nil {
+			g.classes++
+			name := fmt.Sprintf("is%s_%d", rl.name, g.classes)
+			fmt.Fprintf(&classes, "func %s(r rune) bool {\nreturn %s\n}\n\n", name, classExpr(a.class))
+			g.genClassAtom(name, a.quant)
+		} else {
+			g.genLiteralAtom(a.literal, a.quant)
+		}
+	}
+	g.printf("return n, n > 0\n")
+	g.printf("}\n\n")
+	g.buf.Write(classes.Bytes())
+}
+
+func (g *generator) genClassAtom(name string, quant byte) {
+	switch quant {
+	case '?':
+		g.printf("if %s(l.Next()) {\nn++\n} else {\nl.Backup()\n}\n", name)
+		return
+	case 0, '+':
+		g.printf("if !%s(l.Next()) {\nl.Backup()\nreturn n, false\n}\nn++\n", name)
+		if quant == 0 {
+			return
+		}
+	}
+	g.printf("for %s(l.Next()) {\nn++\n}\nl.Backup()\n", name)
+}
+
+func (g *generator) genLiteralAtom(literal string, quant byte) {
+	count := utf8.RuneCountInString(literal)
+	switch quant {
+	case '?':
+		g.printf("if l.AcceptString(%q) {\nn += %d\n}\n", literal, count)
+		return
+	case 0, '+':
+		g.printf("if !l.AcceptString(%q) {\nreturn n, false\n}\nn += %d\n", literal, count)
+		if quant == 0 {
+			return
+		}
+	}
+	g.printf("for l.AcceptString(%q) {\nn += %d\n}\n", literal, count)
+}
+
+// classExpr creates a boolean expression which checks if `r` is in the
+// class. EOF is never part of a class
+func classExpr(c *class) string {
+	if c.any {
+		return "r != lex.EOF"
+	}
+
+	var terms []string
+	for _, rr := range c.ranges {
+		if rr.lo == rr.hi {
+			terms = append(terms, fmt.Sprintf("r == %q", rr.lo))
+			continue
+		}
+		terms = append(terms, fmt.Sprintf("r >= %q && r <= %q", rr.lo, rr.hi))
+	}
+
+	expr := strings.Join(terms, " || ")
+	if c.negate {
+		return fmt.Sprintf("r != lex.EOF && !(%s)", expr)
+	}
+	return expr
+}
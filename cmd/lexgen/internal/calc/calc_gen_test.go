@@ -0,0 +1,279 @@
+// Code generated by lexgen from calc.lex. DO NOT EDIT.
+
+package calc
+
+import "github.com/lestrrat-go/lex"
+
+const (
+	ItemNumber lex.ItemType = lex.ItemDefaultMax + 1 + iota
+	ItemIf
+	ItemIdent
+	ItemQuote
+	ItemText
+	ItemEscape
+	ItemUnquote
+)
+
+func init() {
+	lex.TypeNames[ItemNumber] = "Number"
+	lex.TypeNames[ItemIf] = "If"
+	lex.TypeNames[ItemIdent] = "Ident"
+	lex.TypeNames[ItemQuote] = "Quote"
+	lex.TypeNames[ItemText] = "Text"
+	lex.TypeNames[ItemEscape] = "Escape"
+	lex.TypeNames[ItemUnquote] = "Unquote"
+}
+
+// lexDefault lexes the Default mode
+func lexDefault(l lex.Lexer) lex.LexFn {
+	if l.Peek() == lex.EOF {
+		l.Emit(lex.ItemEOF)
+		return nil
+	}
+
+	best, longest := -1, 0
+	if n, ok := matchSpace(l); ok && n > longest {
+		best, longest = 0, n
+	}
+	if n, ok := matchNumber(l); ok && n > longest {
+		best, longest = 1, n
+	}
+	if n, ok := matchIf(l); ok && n > longest {
+		best, longest = 2, n
+	}
+	if n, ok := matchIdent(l); ok && n > longest {
+		best, longest = 3, n
+	}
+	if n, ok := matchQuote(l); ok && n > longest {
+		best, longest = 4, n
+	}
+	for i := 0; i < longest; i++ {
+		l.Next()
+	}
+
+	switch best {
+	case 0:
+		l.Ignore()
+		return lexDefault
+	case 1:
+		l.Emit(ItemNumber)
+		return lexDefault
+	case 2:
+		l.Emit(ItemIf)
+		return lexDefault
+	case 3:
+		l.Emit(ItemIdent)
+		return lexDefault
+	case 4:
+		l.Emit(ItemQuote)
+		return lexString
+	}
+	return l.EmitErrorf("unexpected character %q", l.Peek())
+}
+
+// lexString lexes the String mode
+func lexString(l lex.Lexer) lex.LexFn {
+	if l.Peek() == lex.EOF {
+		l.Emit(lex.ItemEOF)
+		return nil
+	}
+
+	best, longest := -1, 0
+	if n, ok := matchText(l); ok && n > longest {
+		best, longest = 0, n
+	}
+	if n, ok := matchEscape(l); ok && n > longest {
+		best, longest = 1, n
+	}
+	if n, ok := matchUnquote(l); ok && n > longest {
+		best, longest = 2, n
+	}
+	for i := 0; i < longest; i++ {
+		l.Next()
+	}
+
+	switch best {
+	case 0:
+		l.Emit(ItemText)
+		return lexString
+	case 1:
+		l.Emit(ItemEscape)
+		return lexString
+	case 2:
+		l.Emit(ItemUnquote)
+		return lexDefault
+	}
+	return l.EmitErrorf("unexpected character %q", l.Peek())
+}
+
+func matchSpace(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !isSpace_1(l.Next()) {
+		l.Backup()
+		return n, false
+	}
+	n++
+	for isSpace_1(l.Next()) {
+		n++
+	}
+	l.Backup()
+	return n, n > 0
+}
+
+func isSpace_1(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
+
+func matchNumber(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !isNumber_2(l.Next()) {
+		l.Backup()
+		return n, false
+	}
+	n++
+	for isNumber_2(l.Next()) {
+		n++
+	}
+	l.Backup()
+	if l.AcceptString(".") {
+		n += 1
+	}
+	for isNumber_3(l.Next()) {
+		n++
+	}
+	l.Backup()
+	return n, n > 0
+}
+
+func isNumber_2(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isNumber_3(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func matchIf(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !l.AcceptString("if") {
+		return n, false
+	}
+	n += 2
+	return n, n > 0
+}
+
+func matchIdent(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !isIdent_4(l.Next()) {
+		l.Backup()
+		return n, false
+	}
+	n++
+	for isIdent_5(l.Next()) {
+		n++
+	}
+	l.Backup()
+	return n, n > 0
+}
+
+func isIdent_4(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_'
+}
+
+func isIdent_5(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_'
+}
+
+func matchQuote(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !l.AcceptString("\"") {
+		return n, false
+	}
+	n += 1
+	return n, n > 0
+}
+
+func matchText(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !isText_6(l.Next()) {
+		l.Backup()
+		return n, false
+	}
+	n++
+	for isText_6(l.Next()) {
+		n++
+	}
+	l.Backup()
+	return n, n > 0
+}
+
+func isText_6(r rune) bool {
+	return r != lex.EOF && !(r == '"' || r == '\\')
+}
+
+func matchEscape(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !l.AcceptString("\\") {
+		return n, false
+	}
+	n += 1
+	if !isEscape_7(l.Next()) {
+		l.Backup()
+		return n, false
+	}
+	n++
+	return n, n > 0
+}
+
+func isEscape_7(r rune) bool {
+	return r != lex.EOF
+}
+
+func matchUnquote(l lex.Lexer) (n int, ok bool) {
+	defer func() {
+		for i := 0; i < n; i++ {
+			l.Backup()
+		}
+	}()
+
+	if !l.AcceptString("\"") {
+		return n, false
+	}
+	n += 1
+	return n, n > 0
+}
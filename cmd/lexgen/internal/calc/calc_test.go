@@ -0,0 +1,56 @@
+package calc
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/lex"
+	"github.com/lestrrat-go/lex/lextest"
+)
+
+//go:generate go run ../../main.go ../../gen.go ../../spec.go -o calc_gen_test.go calc.lex
+
+func TestGenerated(t *testing.T) {
+	tests := map[string][]lex.Item{
+		"if iffy 3.14 \"say \\\"hi\\\"\"\n bar_2": {
+			lex.NewItem(ItemIf, 0, 1, "if"),
+			lex.NewItem(ItemIdent, 3, 1, "iffy"),
+			lex.NewItem(ItemNumber, 8, 1, "3.14"),
+			lex.NewItem(ItemQuote, 13, 1, `"`),
+			lex.NewItem(ItemText, 14, 1, "say "),
+			lex.NewItem(ItemEscape, 18, 1, `\"`),
+			lex.NewItem(ItemText, 20, 1, "hi"),
+			lex.NewItem(ItemEscape, 22, 1, `\"`),
+			lex.NewItem(ItemUnquote, 24, 1, `"`),
+			lex.NewItem(ItemIdent, 27, 2, "bar_2"),
+			lex.NewItem(lex.ItemEOF, 32, 2, ""),
+		},
+		"1. + 2": {
+			lex.NewItem(ItemNumber, 0, 1, "1."),
+			lex.NewItem(lex.ItemError, 3, 1, "unexpected character '+'"),
+		},
+	}
+
+	for input, expected := range tests {
+		if _, err := lextest.Check(lexDefault, []byte(input)); err != nil {
+			t.Errorf("%q: %s", input, err)
+		}
+
+		l := lex.New(lex.StringSource(input), lexDefault)
+		go l.Run()
+
+		i := 0
+		for item := l.NextItem(); item != nil; item = l.NextItem() {
+			if i >= len(expected) {
+				t.Fatalf("%q: unexpected item %#v", input, item)
+			}
+			e := expected[i]
+			if e.Type() != item.Type() || e.Pos() != item.Pos() || e.Line() != item.Line() || e.Value() != item.Value() {
+				t.Errorf("%q: expected %s %q at %d (line %d), got %s %q at %d (line %d)", input, e.Type(), e.Value(), e.Pos(), e.Line(), item.Type(), item.Value(), item.Pos(), item.Line())
+			}
+			i++
+		}
+		if i != len(expected) {
+			t.Errorf("%q: expected %d items, got %d", input, len(expected), i)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSpec = `# test spec
+package calc
+prefix Tok
+
+skip  Space   [ \t\n]+
+token Number  [0-9]+ "."? [0-9]*
+token Quote   "\"" -> String
+
+mode String
+token Text    [^"\\]+
+token Unquote "\"" -> Default
+`
+
+func TestParseSpec(t *testing.T) {
+	s, err := parseSpec(strings.NewReader(testSpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if s.pkg != "calc" || s.prefix != "Tok" {
+		t.Errorf("expected package calc and prefix Tok, got %s and %s", s.pkg, s.prefix)
+	}
+	if len(s.modes) != 2 || s.modes[0].name != "Default" || s.modes[1].name != "String" {
+		t.Fatalf("expected modes Default and String, got %v", s.modes)
+	}
+
+	number := s.modes[0].rules[1]
+	if len(number.atoms) != 3 || number.atoms[0].quant != '+' || number.atoms[1].literal != "." || number.atoms[1].quant != '?' {
+		t.Errorf("unexpected atoms for Number: %+v", number.atoms)
+	}
+
+	text := s.modes[1].rules[0].atoms[0].class
+	if !text.negate || len(text.ranges) != 2 || text.ranges[0].lo != '"' || text.ranges[1].lo != '\\' {
+		t.Errorf("unexpected class for Text: %+v", text)
+	}
+	if s.modes[1].rules[1].next != "Default" {
+		t.Errorf("expected Unquote to switch to Default")
+	}
+}
+
+func TestParseSpec_Errors(t *testing.T) {
+	tests := map[string]string{
+		"package calc\ntoken A \"a\" -> Nowhere":     "line 2: unknown mode Nowhere",
+		"package calc\ntoken A \"a\"\ntoken A \"b\"": "line 3: duplicate rule A (first defined at line 2)",
+		"package calc\ntoken A [a-":                  "line 2: unterminated character class",
+		"package calc\ntoken A [z-a]":                "line 2: invalid range 'z'-'a' in character class",
+		"package calc\ntoken A \"\"":                 "line 2: empty string literal",
+		"package calc\nfoo":                          "line 2: unknown directive \"foo\"",
+		"token A \"a\"":                              "line 1: missing package directive",
+		"package calc\nmode foo\nmode Foo":           "line 3: mode Foo differs from mode foo only in case",
+	}
+
+	for src, expected := range tests {
+		_, err := parseSpec(strings.NewReader(src))
+		if err == nil {
+			t.Errorf("%q: expected an error", src)
+			continue
+		}
+		if err.Error() != expected {
+			t.Errorf("%q: expected %q, got %q", src, expected, err)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s, err := parseSpec(strings.NewReader(testSpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	src, err := generate(s, "calc.lex")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{
+		"// Code generated by lexgen from calc.lex. DO NOT EDIT.",
+		"TokNumber lex.ItemType = lex.ItemDefaultMax + 1 + iota",
+		`lex.TypeNames[TokUnquote] = "Unquote"`,
+		"func lexDefault(l lex.Lexer) lex.LexFn {",
+		"func lexString(l lex.Lexer) lex.LexFn {",
+		"func matchSpace(l lex.Lexer) (n int, ok bool) {",
+		"return r != lex.EOF && !(r == '\"' || r == '\\\\')",
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected generated source to contain %q", expected)
+		}
+	}
+	if bytes.Contains(src, []byte("TokSpace")) {
+		t.Errorf("expected no ItemType for skip rules")
+	}
+}
+
+// TestGenerate_Fixture makes sure that the lexer in internal/calc, which
+// is compiled and run by its own tests, is what lexgen currently generates
+func TestGenerate_Fixture(t *testing.T) {
+	f, err := os.Open(filepath.Join("internal", "calc", "calc.lex"))
+	if err != nil {
+		t.Fatalf("failed to open spec: %s", err)
+	}
+	defer f.Close()
+
+	s, err := parseSpec(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	src, err := generate(s, "calc.lex")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fixture, err := ioutil.ReadFile(filepath.Join("internal", "calc", "calc_gen_test.go"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %s", err)
+	}
+	if !bytes.Equal(src, fixture) {
+		t.Errorf("internal/calc/calc_gen_test.go is out of date, run go generate in internal/calc")
+	}
+}
+
+// TestGenerate_Compiles type checks the generated source for specs whose
+// names used to collide in the generated code
+func TestGenerate_Compiles(t *testing.T) {
+	specs := map[string]string{
+		"lower case mode": "package calc\nmode foo\ntoken A \"a\" -> Bar\nmode Bar\ntoken B \"b\" -> foo\n",
+		"class counters":  "package calc\ntoken X1 [a]\ntoken X [b] [b] [b] [b] [b] [b] [b] [b] [b] [b]\n",
+	}
+
+	for name, src := range specs {
+		s, err := parseSpec(strings.NewReader(src))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		gen, err := generate(s, "test.lex")
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "test_gen.go", gen, 0)
+		if err != nil {
+			t.Errorf("%s: failed to parse generated source: %s", name, err)
+			continue
+		}
+		conf := types.Config{Importer: importer.For("source", nil)}
+		if _, err := conf.Check("calc", fset, []*ast.File{f}, nil); err != nil {
+			t.Errorf("%s: generated source does not compile: %s\n%s", name, err, gen)
+		}
+	}
+}
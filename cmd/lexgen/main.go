@@ -0,0 +1,102 @@
+/*
+lexgen generates lexers from a spec file. The generated source contains
+an ItemType constant for each token, registers their names in
+lex.TypeNames, and defines a LexFn for each mode. It is meant to be used
+with go generate:
+
+	//go:generate lexgen -o tokens_gen.go tokens.lex
+
+A spec file looks like this:
+
+	# comments start with '#'
+	package calc
+	prefix Item
+
+	mode Default
+	skip  Space   [ \t\r\n]+
+	token Number  [0-9]+ "."? [0-9]*
+	token If      "if"
+	token Ident   [a-zA-Z_] [a-zA-Z0-9_]*
+	token Quote   "\"" -> String
+
+	mode String
+	token Text    [^"\\]+
+	token Escape  "\\" .
+	token Unquote "\"" -> Default
+
+"package" sets the package name of the generated source, and "prefix"
+the prefix of the ItemType constants ("Item" by default), so the rules
+above produce ItemNumber, ItemIf and so on. Each "token" rule emits an
+item of its own type, while "skip" rules discard what they match.
+
+A pattern is a sequence of string literals (using Go syntax), character
+classes such as [a-z_] or [^"\n], and '.' which matches any rune. Each
+of them may be followed by '?', '*' or '+'. Quantifiers are greedy, and
+never give back what they matched: [a-z]* "z" never matches.
+
+Rules are grouped into modes. "-> Mode" at the end of a rule switches to
+the given mode after the rule matched. Rules that appear before any mode
+directive belong to the mode "Default". The first mode is the initial
+one, and each mode Foo is lexed by a LexFn named lexFoo, so the lexer
+above is created with:
+
+	l := lex.New(lex.StringSource(input), lexDefault)
+
+Within a mode, the rule with the longest match wins. If several rules
+match the same number of runes, the one defined first wins, so keywords
+must be defined before the identifiers that they look like.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	if err := _main(); err != nil {
+		fmt.Fprintf(os.Stderr, "lexgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func _main() error {
+	var output string
+	flag.StringVar(&output, "o", "", "output file (default: standard output)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: lexgen [-o output] spec\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := flag.Arg(0)
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := parseSpec(f)
+	if err != nil {
+		return fmt.Errorf("%s: %s", input, err)
+	}
+
+	src, err := generate(s, filepath.Base(input))
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(output, src, 0644)
+}
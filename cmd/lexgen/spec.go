@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// spec is a parsed spec file
+type spec struct {
+	pkg    string
+	prefix string
+	modes  []*mode
+}
+
+type mode struct {
+	name  string
+	rules []*rule
+}
+
+type rule struct {
+	name  string
+	skip  bool
+	atoms []atom
+	next  string // name of the mode to switch to, if any
+	line  int
+}
+
+// atom is a single element of a pattern, along with its quantifier
+// ('?', '*', '+', or 0 for exactly once). Exactly one of literal or
+// class is set
+type atom struct {
+	literal string
+	class   *class
+	quant   byte
+}
+
+type runeRange struct {
+	lo, hi rune
+}
+
+type class struct {
+	negate bool
+	any    bool
+	ranges []runeRange
+}
+
+type specError struct {
+	line int
+	msg  string
+}
+
+func (e *specError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+func errorf(line int, format string, args ...interface{}) error {
+	return &specError{line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// parseSpec parses a spec file. See the package documentation for
+// the format
+func parseSpec(r io.Reader) (*spec, error) {
+	s := &spec{prefix: "Item"}
+	names := make(map[string]int)
+	var cur *mode
+
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "package", "prefix":
+			if len(fields) != 2 || !isIdent(fields[1]) {
+				return nil, errorf(lineno, "expected '%s <identifier>'", fields[0])
+			}
+			if fields[0] == "package" {
+				s.pkg = fields[1]
+			} else {
+				s.prefix = fields[1]
+			}
+		case "mode":
+			if len(fields) != 2 || !isIdent(fields[1]) {
+				return nil, errorf(lineno, "expected 'mode <identifier>'")
+			}
+			for _, m := range s.modes {
+				switch {
+				case m.name == fields[1]:
+					return nil, errorf(lineno, "duplicate mode %s", fields[1])
+				case strings.EqualFold(m.name, fields[1]):
+					// both would be lexed by the same LexFn
+					return nil, errorf(lineno, "mode %s differs from mode %s only in case", fields[1], m.name)
+				}
+			}
+			cur = &mode{name: fields[1]}
+			s.modes = append(s.modes, cur)
+		case "token", "skip":
+			if len(fields) < 3 || !isIdent(fields[1]) {
+				return nil, errorf(lineno, "expected '%s <identifier> <pattern>'", fields[0])
+			}
+			if prev, ok := names[fields[1]]; ok {
+				return nil, errorf(lineno, "duplicate rule %s (first defined at line %d)", fields[1], prev)
+			}
+			names[fields[1]] = lineno
+
+			rest := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, fields[1]))
+			rl, err := parseRule(rest, lineno)
+			if err != nil {
+				return nil, err
+			}
+			rl.name = fields[1]
+			rl.skip = fields[0] == "skip"
+
+			if cur == nil {
+				cur = &mode{name: "Default"}
+				s.modes = append(s.modes, cur)
+			}
+			cur.rules = append(cur.rules, rl)
+		default:
+			return nil, errorf(lineno, "unknown directive %q", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.pkg == "" {
+		return nil, errorf(1, "missing package directive")
+	}
+	if len(s.modes) == 0 {
+		return nil, errorf(1, "no rules defined")
+	}
+	for _, m := range s.modes {
+		for _, rl := range m.rules {
+			if rl.next != "" && s.mode(rl.next) == nil {
+				return nil, errorf(rl.line, "unknown mode %s", rl.next)
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *spec) mode(name string) *mode {
+	for _, m := range s.modes {
+		if m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// parseRule parses the pattern of a rule, and the optional mode switch
+func parseRule(src string, lineno int) (*rule, error) {
+	rl := &rule{line: lineno}
+	for {
+		src = strings.TrimLeft(src, " \t")
+		if src == "" {
+			break
+		}
+
+		var a atom
+		switch src[0] {
+		case '-':
+			if !strings.HasPrefix(src, "->") {
+				return nil, errorf(lineno, "unexpected %q", src[0])
+			}
+			next := strings.TrimSpace(src[2:])
+			if !isIdent(next) {
+				return nil, errorf(lineno, "expected '-> <mode>'")
+			}
+			rl.next = next
+			src = ""
+			continue
+		case '"', '`':
+			quoted := quotedPrefix(src)
+			var err error
+			if a.literal, err = strconv.Unquote(quoted); err != nil {
+				return nil, errorf(lineno, "invalid string literal: %s", quoted)
+			}
+			if a.literal == "" {
+				return nil, errorf(lineno, "empty string literal")
+			}
+			src = src[len(quoted):]
+		case '[':
+			c, n, err := parseClass(src)
+			if err != nil {
+				return nil, errorf(lineno, "%s", err)
+			}
+			a.class = c
+			src = src[n:]
+		case '.':
+			a.class = &class{any: true}
+			src = src[1:]
+		default:
+			return nil, errorf(lineno, "unexpected %q", src[0])
+		}
+
+		if src != "" && strings.IndexByte("?*+", src[0]) >= 0 {
+			a.quant = src[0]
+			src = src[1:]
+		}
+		rl.atoms = append(rl.atoms, a)
+	}
+
+	if len(rl.atoms) == 0 {
+		return nil, errorf(lineno, "empty pattern")
+	}
+	return rl, nil
+}
+
+// quotedPrefix returns the quoted string at the beginning of `src`,
+// without validating it
+func quotedPrefix(src string) string {
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if src[0] == '"' {
+				i++
+			}
+		case src[0]:
+			return src[:i+1]
+		}
+	}
+	return src
+}
+
+// parseClass parses a character class such as "[^a-z_]", and returns the
+// number of bytes it spans
+func parseClass(src string) (*class, int, error) {
+	c := &class{}
+	i := 1
+	if strings.HasPrefix(src[i:], "^") {
+		c.negate = true
+		i++
+	}
+
+	next := func() (rune, bool, error) {
+		if i >= len(src) {
+			return 0, false, fmt.Errorf("unterminated character class")
+		}
+		r, w := utf8.DecodeRuneInString(src[i:])
+		i += w
+		if r != '\\' {
+			return r, false, nil
+		}
+
+		if i >= len(src) {
+			return 0, false, fmt.Errorf("unterminated character class")
+		}
+		r, w = utf8.DecodeRuneInString(src[i:])
+		i += w
+		switch r {
+		case 'n':
+			r = '\n'
+		case 'r':
+			r = '\r'
+		case 't':
+			r = '\t'
+		case '\\', ']', '[', '-', '^':
+		default:
+			return 0, false, fmt.Errorf("invalid escape \\%c in character class", r)
+		}
+		return r, true, nil
+	}
+
+	for {
+		lo, escaped, err := next()
+		if err != nil {
+			return nil, 0, err
+		}
+		if lo == ']' && !escaped {
+			break
+		}
+
+		hi := lo
+		if strings.HasPrefix(src[i:], "-") && !strings.HasPrefix(src[i:], "-]") {
+			i++
+			if hi, _, err = next(); err != nil {
+				return nil, 0, err
+			}
+			if hi < lo {
+				return nil, 0, fmt.Errorf("invalid range %q-%q in character class", lo, hi)
+			}
+		}
+		c.ranges = append(c.ranges, runeRange{lo, hi})
+	}
+
+	if len(c.ranges) == 0 {
+		return nil, 0, fmt.Errorf("empty character class")
+	}
+	return c, i, nil
+}
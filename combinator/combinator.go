@@ -0,0 +1,276 @@
+/*
+Package combinator provides parser combinators over streams of LexItems.
+Small parsers, such as Token, are composed into larger ones using Seq,
+Choice, Many, Optional and Map. Parsers backtrack freely: a failed
+alternative rewinds the Stream before the next one is tried.
+
+	number := combinator.Map(combinator.Token(ItemNumber), func(v interface{}) (interface{}, error) {
+	  return strconv.Atoi(v.(lex.LexItem).Value())
+	})
+	list := combinator.Seq(number, combinator.Many(combinator.Seq(combinator.Token(ItemComma), number)))
+	v, err := combinator.Parse(list, l)
+*/
+package combinator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Stream buffers the items read from an ItemSource, so that parsers can
+// rewind to an earlier position using Mark and Reset
+type Stream struct {
+	src   lex.ItemSource
+	items []lex.LexItem
+	pos   int
+	done  bool
+	err   *Error
+}
+
+// NewStream creates a new Stream reading from `src`
+func NewStream(src lex.ItemSource) *Stream {
+	return &Stream{src: src}
+}
+
+func (s *Stream) fill() bool {
+	if s.pos < len(s.items) {
+		return true
+	}
+	if s.done {
+		return false
+	}
+	item := s.src.NextItem()
+	if item == nil {
+		s.done = true
+		return false
+	}
+	s.items = append(s.items, item)
+	return true
+}
+
+// Peek returns the next item without consuming it, or nil at the end of
+// the input
+func (s *Stream) Peek() lex.LexItem {
+	if !s.fill() {
+		return nil
+	}
+	return s.items[s.pos]
+}
+
+// Next consumes and returns the next item, or nil at the end of the input
+func (s *Stream) Next() lex.LexItem {
+	if !s.fill() {
+		return nil
+	}
+	s.pos++
+	return s.items[s.pos-1]
+}
+
+// Mark returns the current position, to be passed to Reset
+func (s *Stream) Mark() int {
+	return s.pos
+}
+
+// Reset rewinds the Stream to a position returned by Mark
+func (s *Stream) Reset(mark int) {
+	s.pos = mark
+}
+
+// fail records an error at the current position
+func (s *Stream) fail(expected string) error {
+	return s.record(&Error{Item: s.Peek(), Expected: []string{expected}, index: s.pos})
+}
+
+// record keeps track of `err`. Of all the errors produced while
+// backtracking, the one furthest into the input is the most helpful, so
+// that is the one that is returned
+func (s *Stream) record(err *Error) error {
+	switch {
+	case s.err == nil || s.err.index < err.index:
+		s.err = err
+	case s.err.index == err.index:
+		if s.err.Msg == "" && err.Msg != "" {
+			s.err.Msg = err.Msg
+		}
+	outer:
+		for _, expected := range err.Expected {
+			for _, e := range s.err.Expected {
+				if e == expected {
+					continue outer
+				}
+			}
+			s.err.Expected = append(s.err.Expected, expected)
+		}
+	}
+	return s.err
+}
+
+// Error is a parse error. Item is the offending item, or nil if the
+// input ended prematurely. Expected lists what would have been accepted
+// in its place, unless Msg is set
+type Error struct {
+	Item     lex.LexItem
+	Expected []string
+	Msg      string
+	index    int
+}
+
+// Error returns the string representation of the error
+func (e *Error) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		expected := append([]string{}, e.Expected...)
+		sort.Strings(expected)
+		got := "end of input"
+		if e.Item != nil {
+			switch e.Item.Type() {
+			case lex.ItemEOF:
+				got = "EOF"
+			case lex.ItemError:
+				got = e.Item.Value()
+			default:
+				got = fmt.Sprintf("%s (%q)", e.Item.Type(), e.Item.Value())
+			}
+		}
+		msg = fmt.Sprintf("expected %s, got %s", strings.Join(expected, " or "), got)
+	}
+
+	if e.Item == nil {
+		return msg
+	}
+	return fmt.Sprintf("line %d, pos %d: %s", e.Item.Line(), e.Item.Pos(), msg)
+}
+
+// Parser parses a part of the input from the Stream. On failure, the
+// Stream may be left at any position: the combinators take care of
+// rewinding it
+type Parser func(s *Stream) (interface{}, error)
+
+// Parse runs `p` on the items from `src`, and fails unless the whole
+// input was consumed
+func Parse(p Parser, src lex.ItemSource) (interface{}, error) {
+	s := NewStream(src)
+	v, err := p(s)
+	if err != nil {
+		return nil, err
+	}
+	if item := s.Peek(); item != nil && item.Type() != lex.ItemEOF {
+		return nil, s.fail("EOF")
+	}
+	return v, nil
+}
+
+// Token creates a Parser that accepts a single item of type `t`, and
+// returns it as a lex.LexItem
+func Token(t lex.ItemType) Parser {
+	return func(s *Stream) (interface{}, error) {
+		item := s.Peek()
+		if item == nil || item.Type() != t {
+			return nil, s.fail(t.String())
+		}
+		return s.Next(), nil
+	}
+}
+
+// Seq creates a Parser that runs each of `parsers` in order, and returns
+// their results as a []interface{}
+func Seq(parsers ...Parser) Parser {
+	return func(s *Stream) (interface{}, error) {
+		results := make([]interface{}, 0, len(parsers))
+		for _, p := range parsers {
+			v, err := p(s)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, v)
+		}
+		return results, nil
+	}
+}
+
+// Choice creates a Parser that returns the result of the first of
+// `parsers` that succeeds
+func Choice(parsers ...Parser) Parser {
+	return func(s *Stream) (interface{}, error) {
+		mark := s.Mark()
+		var err error
+		for _, p := range parsers {
+			var v interface{}
+			if v, err = p(s); err == nil {
+				return v, nil
+			}
+			s.Reset(mark)
+		}
+		return nil, err
+	}
+}
+
+// Many creates a Parser that runs `p` as many times as possible, and
+// returns the results as a []interface{}. It never fails
+func Many(p Parser) Parser {
+	return func(s *Stream) (interface{}, error) {
+		var results []interface{}
+		for {
+			mark := s.Mark()
+			v, err := p(s)
+			if err != nil || s.Mark() == mark {
+				s.Reset(mark)
+				return results, nil
+			}
+			results = append(results, v)
+		}
+	}
+}
+
+// Optional creates a Parser that returns the result of `p`, or nil if
+// `p` fails
+func Optional(p Parser) Parser {
+	return func(s *Stream) (interface{}, error) {
+		mark := s.Mark()
+		v, err := p(s)
+		if err != nil {
+			s.Reset(mark)
+			return nil, nil
+		}
+		return v, nil
+	}
+}
+
+// Map creates a Parser that transforms the result of `p` using `fn`.
+// An error returned by `fn` is positioned at the first item that `p`
+// consumed
+func Map(p Parser, fn func(interface{}) (interface{}, error)) Parser {
+	return func(s *Stream) (interface{}, error) {
+		mark := s.Mark()
+		v, err := p(s)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = fn(v); err != nil {
+			if _, ok := err.(*Error); ok {
+				return nil, err
+			}
+			end := s.Mark()
+			s.Reset(mark)
+			perr := &Error{Item: s.Peek(), Msg: err.Error(), index: mark}
+			s.Reset(end)
+			return nil, s.record(perr)
+		}
+		return v, nil
+	}
+}
+
+// Lazy creates a Parser that calls `fn` to create the actual Parser the
+// first time it is run. Use it to write recursive grammars
+func Lazy(fn func() Parser) Parser {
+	var p Parser
+	return func(s *Stream) (interface{}, error) {
+		if p == nil {
+			p = fn()
+		}
+		return p(s)
+	}
+}
@@ -0,0 +1,153 @@
+package combinator_test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/lex"
+	"github.com/lestrrat-go/lex/combinator"
+)
+
+const (
+	ItemNumber lex.ItemType = lex.ItemDefaultMax + iota
+	ItemComma
+	ItemLParen
+	ItemRParen
+)
+
+func lexList(l lex.Lexer) lex.LexFn {
+	l.AcceptRun(" \n")
+	l.Ignore()
+
+	switch r := l.Next(); {
+	case r == lex.EOF:
+		l.Emit(lex.ItemEOF)
+		return nil
+	case r >= '0' && r <= '9':
+		l.AcceptRun("0123456789")
+		l.Emit(ItemNumber)
+	case r == ',':
+		l.Emit(ItemComma)
+	case r == '(':
+		l.Emit(ItemLParen)
+	case r == ')':
+		l.Emit(ItemRParen)
+	default:
+		return l.EmitErrorf("unexpected character %q", r)
+	}
+	return lexList
+}
+
+// list := "(" [ elem { "," elem } ] ")"
+// elem := number | list
+func listParser() combinator.Parser {
+	number := combinator.Map(combinator.Token(ItemNumber), func(v interface{}) (interface{}, error) {
+		n, err := strconv.Atoi(v.(lex.LexItem).Value())
+		if err != nil {
+			return nil, err
+		}
+		if n > 255 {
+			return nil, errors.New("number out of range")
+		}
+		return n, nil
+	})
+
+	var list combinator.Parser
+	elem := combinator.Choice(number, combinator.Lazy(func() combinator.Parser { return list }))
+	elems := combinator.Map(
+		combinator.Seq(elem, combinator.Many(combinator.Seq(combinator.Token(ItemComma), elem))),
+		func(v interface{}) (interface{}, error) {
+			seq := v.([]interface{})
+			result := []interface{}{seq[0]}
+			for _, rest := range seq[1].([]interface{}) {
+				result = append(result, rest.([]interface{})[1])
+			}
+			return result, nil
+		},
+	)
+	list = combinator.Map(
+		combinator.Seq(combinator.Token(ItemLParen), combinator.Optional(elems), combinator.Token(ItemRParen)),
+		func(v interface{}) (interface{}, error) {
+			if elems := v.([]interface{})[1]; elems != nil {
+				return elems, nil
+			}
+			return []interface{}{}, nil
+		},
+	)
+	return list
+}
+
+func parse(input string) (interface{}, error) {
+	l := lex.NewStringLexer(input, lexList)
+	go l.Run()
+	return combinator.Parse(listParser(), l)
+}
+
+func TestCombinators(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"()", "[]"},
+		{"(1)", "[1]"},
+		{"(1, 2, 3)", "[1 2 3]"},
+		{"(1, (2, ()), 3)", "[1 [2 []] 3]"},
+	}
+
+	for _, test := range tests {
+		v, err := parse(test.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.input, err)
+			continue
+		}
+		if s := fmt.Sprint(v); s != test.expect {
+			t.Errorf("%q: expected %s, got %s", test.input, test.expect, s)
+		}
+	}
+}
+
+func TestCombinators_Errors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"(1, 2", "line 1, pos 5: expected "},
+		{"(1,\n,)", "line 2, pos 4: expected "},
+		{"(1) 2", "line 1, pos 4: expected EOF, got "},
+		{"(1, 256)", "line 1, pos 4: number out of range"},
+		{"(1, $)", "line 1, pos 5: expected "},
+	}
+
+	for _, test := range tests {
+		_, err := parse(test.input)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.input)
+			continue
+		}
+		if _, ok := err.(*combinator.Error); !ok {
+			t.Errorf("%q: expected *combinator.Error, got %T", test.input, err)
+		}
+		if !strings.HasPrefix(err.Error(), test.expect) {
+			t.Errorf("%q: expected error starting with %q, got %q", test.input, test.expect, err)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	l := lex.NewStringLexer("1, 2", lexList)
+	go l.Run()
+
+	s := combinator.NewStream(l)
+	mark := s.Mark()
+	if item := s.Next(); item.Value() != "1" {
+		t.Errorf("expected 1, got %s", item)
+	}
+	s.Next()
+	s.Reset(mark)
+	if item := s.Peek(); item.Value() != "1" {
+		t.Errorf("expected 1 after Reset, got %s", item)
+	}
+}
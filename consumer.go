@@ -10,13 +10,15 @@ type Consumer interface {
 
 // ItemConsume is a simple Consumer impementation.
 type ItemConsume struct {
-	lexer     Lexer
+	lexer     ItemSource
 	items     [3]LexItem
 	peekCount int
 }
 
-// NewItemConsume creates a new ItemConsume instance
-func NewItemConsume(l Lexer) *ItemConsume {
+// NewItemConsume creates a new ItemConsume instance. The items are read
+// from `l`, which is usually a Lexer, but can be any ItemSource, such as
+// the stream operators Filter, Drop, Map and Merge
+func NewItemConsume(l ItemSource) *ItemConsume {
 	return &ItemConsume{
 		l,
 		[3]LexItem{},
@@ -0,0 +1,345 @@
+/*
+Package dfa compiles a set of token rules into a single deterministic
+finite automaton, and lexes with it using a table driven loop.
+
+Each Rule has a pattern written in a subset of the regular expression
+syntax: literals, escapes (\n, \t, \d, \w, \s and their negations, or
+any escaped punctuation), '.' (any rune except newline), character
+classes such as [a-z_] or [^"\n], grouping with (), alternation with |,
+and the *, + and ? quantifiers. Anchors, counted repetitions and
+backreferences are not supported.
+
+The Machine always picks the longest match. When several rules match the
+same text, the one that comes first in the list wins, so keywords must
+come before identifiers:
+
+	m, err := dfa.Compile([]dfa.Rule{
+	  {Pattern: `if|else`, Type: ItemKeyword},
+	  {Pattern: `[a-zA-Z_]\w*`, Type: ItemIdent},
+	  {Pattern: `\s+`, Skip: true},
+	  {Pattern: `"`, Action: lexString},
+	})
+	l := lex.New(lex.StringSource(input), m.Lex)
+
+The Machine drives any Lexer through Next and Backup, so it works with
+StringLexer as well as ReaderLexer. Context sensitive parts can be lexed
+by hand: a Rule with an Action hands control to the Action once the rule
+matched, and the Action returns m.Lex when it is done.
+*/
+package dfa
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Rule describes a token
+type Rule struct {
+	// Pattern is the regular expression that the token matches
+	Pattern string
+	// Type is the type of the emitted items
+	Type lex.ItemType
+	// Skip discards the matched text instead of emitting it
+	Skip bool
+	// Action, if set, is called once the rule matched. The matched text
+	// is in the lexer's buffer, and has not been emitted yet. The LexFn
+	// returned by the Action is called next
+	Action lex.LexFn
+}
+
+type nfaEdge struct {
+	chars charSet
+	to    int
+}
+
+type nfaState struct {
+	edges  []nfaEdge
+	eps    []int
+	accept int
+}
+
+type nfa struct {
+	states []nfaState
+}
+
+func (n *nfa) newState() int {
+	n.states = append(n.states, nfaState{accept: -1})
+	return len(n.states) - 1
+}
+
+// build adds the states for `nd` to the NFA, and returns the start and
+// end states of the fragment
+func (n *nfa) build(nd *node) (int, int) {
+	switch nd.kind {
+	case nodeChars:
+		start, end := n.newState(), n.newState()
+		n.states[start].edges = append(n.states[start].edges, nfaEdge{nd.chars, end})
+		return start, end
+	case nodeConcat:
+		start, end := n.build(nd.subs[0])
+		for _, sub := range nd.subs[1:] {
+			s, e := n.build(sub)
+			n.states[end].eps = append(n.states[end].eps, s)
+			end = e
+		}
+		return start, end
+	case nodeAlt:
+		start, end := n.newState(), n.newState()
+		for _, sub := range nd.subs {
+			s, e := n.build(sub)
+			n.states[start].eps = append(n.states[start].eps, s)
+			n.states[e].eps = append(n.states[e].eps, end)
+		}
+		return start, end
+	case nodeStar, nodePlus, nodeQuest:
+		start, end := n.newState(), n.newState()
+		s, e := n.build(nd.subs[0])
+		n.states[start].eps = append(n.states[start].eps, s)
+		n.states[e].eps = append(n.states[e].eps, end)
+		if nd.kind != nodePlus {
+			n.states[start].eps = append(n.states[start].eps, end)
+		}
+		if nd.kind != nodeQuest {
+			n.states[e].eps = append(n.states[e].eps, s)
+		}
+		return start, end
+	default: // nodeEmpty
+		s := n.newState()
+		return s, s
+	}
+}
+
+// closure returns the sorted set of states reachable from `set` through
+// epsilon transitions
+func (n *nfa) closure(set []int) []int {
+	seen := make(map[int]bool)
+	stack := append([]int{}, set...)
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		stack = append(stack, n.states[s].eps...)
+	}
+
+	result := make([]int, 0, len(seen))
+	for s := range seen {
+		result = append(result, s)
+	}
+	sort.Ints(result)
+	return result
+}
+
+type transition struct {
+	lo, hi rune
+	to     int
+}
+
+type state struct {
+	ascii  [utf8.RuneSelf]int32
+	ranges []transition
+	accept int
+}
+
+func (s *state) next(r rune) int {
+	if r < 0 {
+		return -1
+	}
+	if r < utf8.RuneSelf {
+		return int(s.ascii[r])
+	}
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].hi >= r })
+	if i < len(s.ranges) && s.ranges[i].lo <= r {
+		return s.ranges[i].to
+	}
+	return -1
+}
+
+// Machine is a compiled set of Rules
+type Machine struct {
+	rules  []Rule
+	states []state
+	// lex is m.Lex, stored so that returning it doesn't allocate a new
+	// method value for each token
+	lex lex.LexFn
+}
+
+// Compile compiles the rules into a Machine. An error is returned if a
+// pattern is invalid, or if it matches the empty string
+func Compile(rules []Rule) (*Machine, error) {
+	n := &nfa{}
+	start := n.newState()
+	for i, rule := range rules {
+		nd, err := parse(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%q): %s", i, rule.Pattern, err)
+		}
+		s, e := n.build(nd)
+		n.states[e].accept = i
+		n.states[start].eps = append(n.states[start].eps, s)
+	}
+
+	m := &Machine{rules: rules}
+	m.lex = m.Lex
+	ids := make(map[string]int)
+	var queue [][]int
+	add := func(set []int) int {
+		key := setKey(set)
+		if id, ok := ids[key]; ok {
+			return id
+		}
+
+		accept := -1
+		for _, s := range set {
+			if a := n.states[s].accept; a >= 0 && (accept < 0 || a < accept) {
+				accept = a
+			}
+		}
+
+		id := len(m.states)
+		ids[key] = id
+		m.states = append(m.states, state{accept: accept})
+		queue = append(queue, set)
+		return id
+	}
+
+	add(n.closure([]int{start}))
+	if a := m.states[0].accept; a >= 0 {
+		return nil, fmt.Errorf("rule %d (%q) matches the empty string", a, rules[a].Pattern)
+	}
+
+	for id := 0; id < len(queue); id++ {
+		set := queue[id]
+
+		// Split the ranges of all outgoing edges at their boundaries, so
+		// that each resulting range leads to a single set of states
+		var bounds []rune
+		for _, s := range set {
+			for _, e := range n.states[s].edges {
+				for _, r := range e.chars {
+					bounds = append(bounds, r.lo, r.hi+1)
+				}
+			}
+		}
+		sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+		var transitions []transition
+		for i := 0; i+1 < len(bounds); i++ {
+			lo, hi := bounds[i], bounds[i+1]-1
+			if lo > hi {
+				continue
+			}
+
+			var targets []int
+			for _, s := range set {
+				for _, e := range n.states[s].edges {
+					if e.chars.contains(lo) {
+						targets = append(targets, e.to)
+					}
+				}
+			}
+			if len(targets) == 0 {
+				continue
+			}
+
+			to := add(n.closure(targets))
+			if k := len(transitions) - 1; k >= 0 && transitions[k].to == to && transitions[k].hi+1 == lo {
+				transitions[k].hi = hi
+				continue
+			}
+			transitions = append(transitions, transition{lo, hi, to})
+		}
+
+		st := &m.states[id]
+		for i := range st.ascii {
+			st.ascii[i] = -1
+		}
+		for _, t := range transitions {
+			for r := t.lo; r <= t.hi && r < utf8.RuneSelf; r++ {
+				st.ascii[r] = int32(t.to)
+			}
+			if t.hi >= utf8.RuneSelf {
+				if t.lo < utf8.RuneSelf {
+					t.lo = utf8.RuneSelf
+				}
+				st.ranges = append(st.ranges, t)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (s charSet) contains(r rune) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i].hi >= r })
+	return i < len(s) && s[i].lo <= r
+}
+
+func setKey(set []int) string {
+	parts := make([]string, len(set))
+	for i, s := range set {
+		parts[i] = strconv.Itoa(s)
+	}
+	return strings.Join(parts, ",")
+}
+
+// States returns the number of states in the automaton
+func (m *Machine) States() int {
+	return len(m.states)
+}
+
+// match runs the automaton from the cursor, and leaves the cursor at the
+// end of the longest match. Returns the index of the matched rule, or -1
+func (m *Machine) match(l lex.Lexer) int {
+	s, consumed := 0, 0
+	accept, acceptLen := -1, 0
+	for {
+		next := m.states[s].next(l.Next())
+		consumed++
+		if next < 0 {
+			break
+		}
+		s = next
+		if a := m.states[s].accept; a >= 0 {
+			accept, acceptLen = a, consumed
+		}
+	}
+
+	for ; consumed > acceptLen; consumed-- {
+		l.Backup()
+	}
+	return accept
+}
+
+// Lex is a LexFn which lexes a single token using the Machine. It returns
+// itself to lex the next one, the Action of the Rule that matched if it
+// has one, or nil once the input is exhausted. Returning after each token
+// lets the lexer stop in between, e.g. when an item sink fails
+func (m *Machine) Lex(l lex.Lexer) lex.LexFn {
+	if l.Peek() == lex.EOF {
+		l.Emit(lex.ItemEOF)
+		return nil
+	}
+
+	i := m.match(l)
+	if i < 0 {
+		return l.EmitErrorf("unexpected character %q", l.Peek())
+	}
+
+	rule := &m.rules[i]
+	switch {
+	case rule.Action != nil:
+		return rule.Action
+	case rule.Skip:
+		l.Ignore()
+	default:
+		l.Emit(rule.Type)
+	}
+	return m.lex
+}
@@ -0,0 +1,175 @@
+package dfa_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/lex"
+	"github.com/lestrrat-go/lex/dfa"
+)
+
+const (
+	ItemKeyword lex.ItemType = lex.ItemDefaultMax + 1 + iota
+	ItemIdent
+	ItemNumber
+	ItemOperator
+	ItemString
+)
+
+func init() {
+	lex.TypeNames[ItemKeyword] = "Keyword"
+	lex.TypeNames[ItemIdent] = "Ident"
+	lex.TypeNames[ItemNumber] = "Number"
+	lex.TypeNames[ItemOperator] = "Operator"
+	lex.TypeNames[ItemString] = "String"
+}
+
+func compile(t testing.TB) *dfa.Machine {
+	var m *dfa.Machine
+
+	// Strings are lexed by hand, to show how the Machine interoperates
+	// with regular LexFns
+	lexString := func(l lex.Lexer) lex.LexFn {
+		for {
+			switch l.Next() {
+			case '\\':
+				l.Next()
+			case '"':
+				l.Emit(ItemString)
+				return m.Lex
+			case lex.EOF:
+				return l.EmitErrorf("unterminated string")
+			}
+		}
+	}
+
+	m, err := dfa.Compile([]dfa.Rule{
+		{Pattern: `if|else|for`, Type: ItemKeyword},
+		{Pattern: `[a-zA-Z_]\w*`, Type: ItemIdent},
+		{Pattern: `\d+(\.\d+)?([eE][-+]?\d+)?`, Type: ItemNumber},
+		{Pattern: `[-+*/=<>!]=?|&&|\|\|`, Type: ItemOperator},
+		{Pattern: `\s+|//[^\n]*`, Skip: true},
+		{Pattern: `"`, Action: lexString},
+		{Pattern: `λ`, Type: ItemKeyword},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return m
+}
+
+func lexAll(l lex.Lexer) []string {
+	go l.Run()
+	var items []string
+	for item := l.NextItem(); item != nil; item = l.NextItem() {
+		items = append(items, fmt.Sprintf("%s:%q@%d", item.Type(), item.Value(), item.Pos()))
+	}
+	return items
+}
+
+func TestMachine(t *testing.T) {
+	m := compile(t)
+	input := "if iffy >= 3.14e-2 // comment\n&& \"a \\\" b\" != λ x1"
+	expected := []string{
+		`Keyword:"if"@0`,
+		`Ident:"iffy"@3`,
+		`Operator:">="@8`,
+		`Number:"3.14e-2"@11`,
+		`Operator:"&&"@30`,
+		`String:"\"a \\\" b\""@33`,
+		`Operator:"!="@42`,
+		`Keyword:"λ"@45`,
+		`Ident:"x1"@48`,
+		`EOF:""@50`,
+	}
+
+	sources := map[string]lex.Source{
+		"StringSource": lex.StringSource(input),
+		"ReaderSource": lex.ReaderSource(strings.NewReader(input)),
+	}
+	for name, src := range sources {
+		items := lexAll(lex.New(src, m.Lex))
+		if len(items) != len(expected) {
+			t.Errorf("%s: expected %d items, got %d: %v", name, len(expected), len(items), items)
+			continue
+		}
+		for i, item := range items {
+			if item != expected[i] {
+				t.Errorf("%s: item %d: expected %s, got %s", name, i, expected[i], item)
+			}
+		}
+	}
+}
+
+func TestMachine_SinkAbort(t *testing.T) {
+	m := compile(t)
+	input := strings.Repeat("x1 + 2 ", 100)
+
+	count := 0
+	l := lex.New(lex.StringSource(input), m.Lex, lex.WithItemSink(func(item lex.LexItem) error {
+		count++
+		return fmt.Errorf("stop")
+	}))
+	l.Run()
+
+	if count != 1 {
+		t.Errorf("expected the sink to be called once, got %d", count)
+	}
+	if l.Cursor() >= len(input) {
+		t.Errorf("expected lexing to stop early, but the whole input was consumed")
+	}
+}
+
+func TestMachine_MaximalMunch(t *testing.T) {
+	// "1." is not a number by itself, so the machine must fall back to
+	// the last accepting state, and give back the "."
+	m, err := dfa.Compile([]dfa.Rule{
+		{Pattern: `\d+(\.\d+)?`, Type: ItemNumber},
+		{Pattern: `\.`, Type: ItemOperator},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	items := lexAll(lex.New(lex.StringSource("1.x"), m.Lex))
+	expected := []string{`Number:"1"@0`, `Operator:"."@1`, `Error:"unexpected character 'x'"@2`}
+	if strings.Join(items, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := map[string]string{
+		`a*`:    `rule 0 ("a*") matches the empty string`,
+		`(ab`:   `rule 0 ("(ab"): missing closing ) at offset 3`,
+		`[a-`:   `rule 0 ("[a-"): missing closing ] at offset 3`,
+		`[z-a]`: `rule 0 ("[z-a]"): invalid range 'z'-'a' at offset 4`,
+		`*a`:    `rule 0 ("*a"): missing argument to repetition operator '*' at offset 1`,
+		`\q`:    `rule 0 ("\\q"): invalid escape \q at offset 2`,
+	}
+
+	for pattern, expected := range tests {
+		_, err := dfa.Compile([]dfa.Rule{{Pattern: pattern}})
+		if err == nil {
+			t.Errorf("%q: expected an error", pattern)
+			continue
+		}
+		if err.Error() != expected {
+			t.Errorf("%q: expected %q, got %q", pattern, expected, err)
+		}
+	}
+}
+
+func BenchmarkMachine(b *testing.B) {
+	m := compile(b)
+	input := strings.Repeat("for x1 <= 42.5 && ident != \"str\" // c\n", 100)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := lex.New(lex.StringSource(input), m.Lex)
+		go l.Run()
+		for item := l.NextItem(); item != nil; item = l.NextItem() {
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package dfa
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// runeRange is an inclusive range of runes
+type runeRange struct {
+	lo, hi rune
+}
+
+// charSet is a sorted list of non-overlapping, non-adjacent ranges
+type charSet []runeRange
+
+func newCharSet(ranges ...runeRange) charSet {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
+	set := charSet{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &set[len(set)-1]
+		if r.lo <= last.hi+1 {
+			if r.hi > last.hi {
+				last.hi = r.hi
+			}
+			continue
+		}
+		set = append(set, r)
+	}
+	return set
+}
+
+func (s charSet) negate() charSet {
+	var neg charSet
+	next := rune(0)
+	for _, r := range s {
+		if r.lo > next {
+			neg = append(neg, runeRange{next, r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= unicode.MaxRune {
+		neg = append(neg, runeRange{next, unicode.MaxRune})
+	}
+	return neg
+}
+
+type nodeKind int
+
+const (
+	nodeChars nodeKind = iota
+	nodeConcat
+	nodeAlt
+	nodeStar
+	nodePlus
+	nodeQuest
+	nodeEmpty
+)
+
+type node struct {
+	kind  nodeKind
+	chars charSet
+	subs  []*node
+}
+
+// parser parses the supported subset of the regular expression syntax:
+// literals, escapes, '.', character classes, grouping, alternation and
+// the '*', '+' and '?' quantifiers
+type parser struct {
+	src string
+	pos int
+}
+
+func parse(src string) (*node, error) {
+	p := &parser{src: src}
+	n, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return n, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at offset %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *parser) peek() rune {
+	if p.pos >= len(p.src) {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return r
+}
+
+func (p *parser) next() rune {
+	r := p.peek()
+	if r >= 0 {
+		p.pos += utf8.RuneLen(r)
+	}
+	return r
+}
+
+func (p *parser) parseAlt() (*node, error) {
+	var subs []*node
+	for {
+		n, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, n)
+		if p.peek() != '|' {
+			break
+		}
+		p.next()
+	}
+	if len(subs) == 1 {
+		return subs[0], nil
+	}
+	return &node{kind: nodeAlt, subs: subs}, nil
+}
+
+func (p *parser) parseConcat() (*node, error) {
+	var subs []*node
+	for {
+		if r := p.peek(); r < 0 || r == '|' || r == ')' {
+			break
+		}
+		n, err := p.parseRepeat()
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, n)
+	}
+	switch len(subs) {
+	case 0:
+		return &node{kind: nodeEmpty}, nil
+	case 1:
+		return subs[0], nil
+	}
+	return &node{kind: nodeConcat, subs: subs}, nil
+}
+
+func (p *parser) parseRepeat() (*node, error) {
+	n, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var kind nodeKind
+		switch p.peek() {
+		case '*':
+			kind = nodeStar
+		case '+':
+			kind = nodePlus
+		case '?':
+			kind = nodeQuest
+		default:
+			return n, nil
+		}
+		p.next()
+		n = &node{kind: kind, subs: []*node{n}}
+	}
+}
+
+func (p *parser) parseAtom() (*node, error) {
+	switch r := p.next(); r {
+	case '(':
+		n, err := p.parseAlt()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ')' {
+			return nil, p.errorf("missing closing )")
+		}
+		return n, nil
+	case '[':
+		set, err := p.parseClass()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeChars, chars: set}, nil
+	case '.':
+		return &node{kind: nodeChars, chars: newCharSet(runeRange{'\n', '\n'}).negate()}, nil
+	case '\\':
+		set, err := p.parseEscape()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeChars, chars: set}, nil
+	case '*', '+', '?':
+		return nil, p.errorf("missing argument to repetition operator %q", r)
+	default:
+		return &node{kind: nodeChars, chars: charSet{{r, r}}}, nil
+	}
+}
+
+// parseEscape parses the escape sequence following a backslash
+func (p *parser) parseEscape() (charSet, error) {
+	r := p.next()
+	var set charSet
+	switch r {
+	case -1:
+		return nil, p.errorf("trailing backslash")
+	case 'n':
+		set = charSet{{'\n', '\n'}}
+	case 'r':
+		set = charSet{{'\r', '\r'}}
+	case 't':
+		set = charSet{{'\t', '\t'}}
+	case 'f':
+		set = charSet{{'\f', '\f'}}
+	case 'v':
+		set = charSet{{'\v', '\v'}}
+	case 'd', 'D':
+		set = charSet{{'0', '9'}}
+	case 'w', 'W':
+		set = newCharSet(runeRange{'0', '9'}, runeRange{'A', 'Z'}, runeRange{'_', '_'}, runeRange{'a', 'z'})
+	case 's', 'S':
+		set = newCharSet(runeRange{'\t', '\n'}, runeRange{'\f', '\r'}, runeRange{' ', ' '})
+	default:
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return nil, p.errorf("invalid escape \\%c", r)
+		}
+		return charSet{{r, r}}, nil
+	}
+
+	if unicode.IsUpper(r) {
+		set = set.negate()
+	}
+	return set, nil
+}
+
+// parseClass parses a character class. The opening '[' has already been
+// consumed
+func (p *parser) parseClass() (charSet, error) {
+	negate := false
+	if p.peek() == '^' {
+		p.next()
+		negate = true
+	}
+
+	var ranges []runeRange
+	for first := true; ; first = false {
+		r := p.next()
+		switch {
+		case r < 0:
+			return nil, p.errorf("missing closing ]")
+		case r == ']' && !first:
+			set := newCharSet(ranges...)
+			if negate {
+				set = set.negate()
+			}
+			return set, nil
+		case r == '\\':
+			set, err := p.parseEscape()
+			if err != nil {
+				return nil, err
+			}
+			if len(set) > 1 || set[0].lo != set[0].hi {
+				ranges = append(ranges, set...)
+				continue
+			}
+			r = set[0].lo
+		}
+
+		hi := r
+		if p.peek() == '-' && p.pos+1 < len(p.src) && p.src[p.pos+1] != ']' {
+			p.next()
+			if hi = p.next(); hi == '\\' {
+				set, err := p.parseEscape()
+				if err != nil {
+					return nil, err
+				}
+				hi = set[0].lo
+			}
+			if hi < r {
+				return nil, p.errorf("invalid range %q-%q", r, hi)
+			}
+		}
+		ranges = append(ranges, runeRange{r, hi})
+	}
+}
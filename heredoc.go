@@ -0,0 +1,156 @@
+package lex
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Heredocs keeps track of pending here-documents, for shell, Ruby or
+// Perl-like grammars. When your LexFn finds a heredoc operator (e.g.
+// "<<EOF"), call Push with the terminator. Once the line containing the
+// operator has been lexed (including its newline), return the LexFn
+// created by LexBodies, which lexes the bodies of all pending heredocs
+// in order, and then continues with the given LexFn:
+//
+//	case h.Pending() && l.AcceptString("\n"):
+//	  l.Emit(ItemNewline)
+//	  return h.LexBodies(lexLine)
+//
+// For each heredoc, the body is emitted as an Item of the body type, and
+// the line containing the terminator as an Item of the terminator type.
+// The newline following the terminator is discarded.
+type Heredocs struct {
+	bodyType       ItemType
+	terminatorType ItemType
+	pending        []heredoc
+}
+
+type heredoc struct {
+	terminator  string
+	stripIndent bool
+	line        int
+}
+
+// NewHeredocs creates a new Heredocs, which emits the bodies and the
+// terminators using the given ItemTypes
+func NewHeredocs(bodyType, terminatorType ItemType) *Heredocs {
+	return &Heredocs{
+		bodyType:       bodyType,
+		terminatorType: terminatorType,
+	}
+}
+
+// Push records a pending heredoc, whose body starts on the line after the
+// current one, and ends with a line equal to `terminator`.
+//
+// If `stripIndent` is true, the terminator may be indented, and the
+// common leading whitespace of the body lines is removed, as in Ruby's
+// "<<~" heredocs. The body Item keeps the text as is in Value(), and
+// provides the stripped text as a string via Payload()
+func (h *Heredocs) Push(l Lexer, terminator string, stripIndent bool) {
+	h.pending = append(h.pending, heredoc{
+		terminator:  terminator,
+		stripIndent: stripIndent,
+		line:        l.Line(),
+	})
+}
+
+// Pending returns true if there are heredocs whose bodies have not been
+// lexed yet
+func (h *Heredocs) Pending() bool {
+	return len(h.pending) > 0
+}
+
+// LexBodies creates a LexFn that lexes the bodies of all pending heredocs,
+// and then continues with `next`. The cursor must be at the beginning of
+// the line following the heredoc operators, with an empty buffer
+func (h *Heredocs) LexBodies(next LexFn) LexFn {
+	var fn LexFn
+	fn = func(l Lexer) LexFn {
+		if len(h.pending) == 0 {
+			return next
+		}
+
+		hd := h.pending[0]
+		for !hd.matchTerminator(l) {
+			l.AcceptRunExcept("\n")
+			if !l.AcceptString("\n") {
+				return l.EmitErrorf("unterminated heredoc, expected %q (started at line %d)", hd.terminator, hd.line)
+			}
+		}
+		h.pending = h.pending[1:]
+
+		if hd.stripIndent {
+			l.EmitPayload(h.bodyType, stripIndent(l.BufferString()))
+		} else {
+			l.Emit(h.bodyType)
+		}
+
+		l.AcceptRun(" \t")
+		l.AcceptString(hd.terminator)
+		l.Emit(h.terminatorType)
+		if l.AcceptString("\r\n") || l.AcceptString("\n") {
+			l.Ignore()
+		}
+		return fn
+	}
+	return fn
+}
+
+// matchTerminator returns true if the current line is the terminator.
+// The cursor is not moved
+func (hd heredoc) matchTerminator(l Lexer) bool {
+	n := 0
+	if hd.stripIndent {
+		for r := l.Next(); r == ' ' || r == '\t'; r = l.Next() {
+			n++
+		}
+		l.Backup()
+	}
+
+	ok := l.AcceptString(hd.terminator)
+	if ok {
+		n += utf8.RuneCountInString(hd.terminator)
+		switch l.Peek() {
+		case '\n', EOF:
+		case '\r':
+			ok = l.PeekString("\r\n")
+		default:
+			ok = false
+		}
+	}
+
+	for ; n > 0; n-- {
+		l.Backup()
+	}
+	return ok
+}
+
+// stripIndent removes the common leading whitespace from the non-blank
+// lines in `s`
+func stripIndent(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	if indent <= 0 {
+		return s
+	}
+
+	for i, line := range lines {
+		if len(line) >= indent && strings.TrimLeft(line[:indent], " \t") == "" {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,80 @@
+package lex
+
+import (
+	"bytes"
+	"testing"
+)
+
+const (
+	itemHeredocBody = ItemDefaultMax + 100 + iota
+	itemHeredocEnd
+)
+
+func lexHeredocLine(l Lexer) LexFn {
+	h := l.Context().(*Heredocs)
+	switch {
+	case l.Peek() == EOF:
+		l.Emit(ItemEOF)
+		return nil
+	case l.AcceptString("\n"):
+		l.Emit(ItemNewline)
+		if h.Pending() {
+			return h.LexBodies(lexHeredocLine)
+		}
+	case l.AcceptRun(" "):
+		l.Ignore()
+	case l.AcceptString("<<"):
+		strip := l.AcceptString("~")
+		l.Emit(ItemOperator)
+		l.AcceptRunExcept(" \n")
+		h.Push(l, l.BufferString(), strip)
+		l.Emit(ItemNumber)
+	default:
+		l.AcceptRunExcept(" \n")
+		l.Emit(ItemNumber)
+	}
+	return lexHeredocLine
+}
+
+func TestHeredocs(t *testing.T) {
+	const input = "cat <<A <<~B x\nline1\nA\n    foo\n      bar\n  B\nend"
+	expected := []Item{
+		NewItem(ItemNumber, 0, 1, "cat"),
+		NewItem(ItemOperator, 4, 1, "<<"),
+		NewItem(ItemNumber, 6, 1, "A"),
+		NewItem(ItemOperator, 8, 1, "<<~"),
+		NewItem(ItemNumber, 11, 1, "B"),
+		NewItem(ItemNumber, 13, 1, "x"),
+		NewItem(ItemNewline, 14, 1, "\n"),
+		NewItem(itemHeredocBody, 15, 2, "line1\n"),
+		NewItem(itemHeredocEnd, 21, 3, "A"),
+		NewItemWithPayload(itemHeredocBody, 23, 4, "    foo\n      bar\n", "foo\n  bar\n"),
+		NewItem(itemHeredocEnd, 41, 6, "  B"),
+		NewItem(ItemNumber, 45, 7, "end"),
+		NewItem(ItemEOF, 48, 7, ""),
+	}
+
+	for _, l := range []Lexer{
+		NewStringLexer(input, lexHeredocLine),
+		NewReaderLexer(bytes.NewBufferString(input), lexHeredocLine),
+	} {
+		l.SetContext(NewHeredocs(itemHeredocBody, itemHeredocEnd))
+		go l.Run()
+
+		verifyItems(t, expected, collect(l))
+	}
+}
+
+func TestHeredocs_Unterminated(t *testing.T) {
+	l := NewStringLexer("cat <<EOF\nfoo\n EOF\n", lexHeredocLine, WithContext(NewHeredocs(itemHeredocBody, itemHeredocEnd)))
+	go l.Run()
+
+	var last LexItem
+	for item := range l.Items() {
+		last = item
+	}
+	const msg = `unterminated heredoc, expected "EOF" (started at line 1)`
+	if last == nil || last.Type() != ItemError || last.Value() != msg {
+		t.Errorf("expected error %q, got %#v", msg, last)
+	}
+}
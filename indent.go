@@ -0,0 +1,117 @@
+package lex
+
+import "errors"
+
+// IndentTracker implements the off-side rule for indentation-sensitive
+// grammars, such as Python or YAML. It is meant to be called from your
+// LexFn functions: call LineStart when the cursor is at the beginning of
+// a line, Newline when the cursor is at the end of a line, and Finish
+// before emitting ItemEOF.
+//
+// When the indentation increases, an ItemIndent containing the leading
+// whitespace is emitted. When it decreases, a zero-width ItemDedent is
+// emitted for each level that has been closed
+type IndentTracker struct {
+	// AllowMixed allows the indentation of a line to contain both tabs
+	// and spaces. By default this is reported as an error
+	AllowMixed bool
+
+	tabWidth int
+	levels   []int
+}
+
+// ErrMixedIndent is returned by IndentTracker.LineStart when the
+// indentation of a line contains both tabs and spaces
+var ErrMixedIndent = errors.New("inconsistent use of tabs and spaces in indentation")
+
+// ErrInconsistentDedent is returned by IndentTracker.LineStart when the
+// indentation decreases to a level that does not match any outer level
+var ErrInconsistentDedent = errors.New("unindent does not match any outer indentation level")
+
+// NewIndentTracker creates a new IndentTracker. Tabs advance the
+// indentation to the next multiple of `tabWidth`
+func NewIndentTracker(tabWidth int) *IndentTracker {
+	if tabWidth < 1 {
+		tabWidth = 1
+	}
+	return &IndentTracker{
+		tabWidth: tabWidth,
+		levels:   []int{0},
+	}
+}
+
+// Depth returns the number of indentation levels currently open
+func (t *IndentTracker) Depth() int {
+	return len(t.levels) - 1
+}
+
+// LineStart measures the leading whitespace of the current line, and
+// emits ItemIndent or ItemDedent items accordingly. Lines that are blank
+// do not affect the indentation. The cursor must be at the beginning
+// of a line
+func (t *IndentTracker) LineStart(l Lexer) error {
+	width := 0
+	tabs, spaces := false, false
+	for {
+		switch l.Next() {
+		case ' ':
+			spaces = true
+			width++
+			continue
+		case '\t':
+			tabs = true
+			width += t.tabWidth - width%t.tabWidth
+			continue
+		}
+		l.Backup()
+		break
+	}
+
+	switch l.Peek() {
+	case '\n', '\r', EOF:
+		l.Ignore()
+		return nil
+	}
+
+	if tabs && spaces && !t.AllowMixed {
+		return ErrMixedIndent
+	}
+
+	current := t.levels[len(t.levels)-1]
+	switch {
+	case width > current:
+		t.levels = append(t.levels, width)
+		l.Emit(ItemIndent)
+	case width < current:
+		l.Ignore()
+		for width < t.levels[len(t.levels)-1] {
+			t.levels = t.levels[:len(t.levels)-1]
+			l.Emit(ItemDedent)
+		}
+		if width != t.levels[len(t.levels)-1] {
+			return ErrInconsistentDedent
+		}
+	default:
+		l.Ignore()
+	}
+	return nil
+}
+
+// Newline accepts a line terminator ("\n" or "\r\n"), and emits an
+// ItemNewline. Returns false if the cursor is not at the end of a line
+func (t *IndentTracker) Newline(l Lexer) bool {
+	if !l.AcceptString("\n") && !l.AcceptString("\r\n") {
+		return false
+	}
+	l.Emit(ItemNewline)
+	return true
+}
+
+// Finish emits an ItemDedent for each indentation level that is still
+// open. Call this upon reaching EOF, before emitting ItemEOF
+func (t *IndentTracker) Finish(l Lexer) {
+	for len(t.levels) > 1 {
+		t.levels = t.levels[:len(t.levels)-1]
+		l.Emit(ItemDedent)
+	}
+}
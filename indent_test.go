@@ -0,0 +1,87 @@
+package lex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func lexIndentLine(l Lexer) LexFn {
+	if err := l.Context().(*IndentTracker).LineStart(l); err != nil {
+		return l.EmitErrorf("%s", err)
+	}
+	return lexIndentBody
+}
+
+func lexIndentBody(l Lexer) LexFn {
+	t := l.Context().(*IndentTracker)
+	switch {
+	case l.Peek() == EOF:
+		t.Finish(l)
+		l.Emit(ItemEOF)
+		return nil
+	case t.Newline(l):
+		return lexIndentLine
+	case l.AcceptRun(" "):
+		l.Ignore()
+	case l.AcceptRunExcept(" \n"):
+		l.Emit(ItemNumber)
+	}
+	return lexIndentBody
+}
+
+func TestIndentTracker(t *testing.T) {
+	const input = "a\n  b\n    c\n\n  d\ne\n\t f"
+	expected := []Item{
+		NewItem(ItemNumber, 0, 1, "a"),
+		NewItem(ItemNewline, 1, 1, "\n"),
+		NewItem(ItemIndent, 2, 2, "  "),
+		NewItem(ItemNumber, 4, 2, "b"),
+		NewItem(ItemNewline, 5, 2, "\n"),
+		NewItem(ItemIndent, 6, 3, "    "),
+		NewItem(ItemNumber, 10, 3, "c"),
+		NewItem(ItemNewline, 11, 3, "\n"),
+		NewItem(ItemNewline, 12, 4, "\n"),
+		NewItem(ItemDedent, 15, 5, ""),
+		NewItem(ItemNumber, 15, 5, "d"),
+		NewItem(ItemNewline, 16, 5, "\n"),
+		NewItem(ItemDedent, 17, 6, ""),
+		NewItem(ItemNumber, 17, 6, "e"),
+		NewItem(ItemNewline, 18, 6, "\n"),
+		NewItem(ItemIndent, 19, 7, "\t "),
+		NewItem(ItemNumber, 21, 7, "f"),
+		NewItem(ItemDedent, 22, 7, ""),
+		NewItem(ItemEOF, 22, 7, ""),
+	}
+
+	for _, l := range []Lexer{
+		NewStringLexer(input, lexIndentLine),
+		NewReaderLexer(bytes.NewBufferString(input), lexIndentLine),
+	} {
+		tracker := NewIndentTracker(4)
+		tracker.AllowMixed = true
+		l.SetContext(tracker)
+		go l.Run()
+
+		verifyItems(t, expected, collect(l))
+	}
+}
+
+func TestIndentTracker_Errors(t *testing.T) {
+	tests := map[string]string{
+		"a\n \tb":         ErrMixedIndent.Error(),
+		"a\n    b\n  c\n": ErrInconsistentDedent.Error(),
+	}
+
+	for input, msg := range tests {
+		l := NewStringLexer(input, lexIndentLine, WithContext(NewIndentTracker(8)))
+		go l.Run()
+
+		var last LexItem
+		for item := range l.Items() {
+			last = item
+		}
+		if last == nil || last.Type() != ItemError || last.Value() != msg {
+			t.Errorf("%q: expected error %q, got %#v", input, msg, last)
+		}
+	}
+}
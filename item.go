@@ -1,7 +1,11 @@
 package lex
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 )
 
 // ItemType describes the type of a LexItem
@@ -21,9 +25,32 @@ const (
 	ItemDefaultMax
 )
 
+// Negative values are reserved for this package, so that its types don't
+// shift ItemDefaultMax, nor collide with your own types. The types emitted
+// by the helpers count down from -1, and the ones that are only used
+// internally count up from itemInternalMin, so the two never meet
+const (
+	// ItemIndent is emitted by IndentTracker when the indentation increases
+	ItemIndent ItemType = -1 - iota
+	// ItemDedent is emitted by IndentTracker when the indentation decreases
+	ItemDedent
+	// ItemNewline is emitted by IndentTracker at the end of each line
+	ItemNewline
+)
+
+const (
+	itemInternalMin ItemType = math.MinInt32 + iota
+	// itemInclude is used internally by StackedLexer to mark the point in
+	// the item stream where an input should be spliced in
+	itemInclude
+)
+
 func init() {
 	TypeNames[ItemEOF] = "EOF"
 	TypeNames[ItemError] = "Error"
+	TypeNames[ItemIndent] = "Indent"
+	TypeNames[ItemDedent] = "Dedent"
+	TypeNames[ItemNewline] = "Newline"
 	TypeNames[ItemDefaultMax] = "Special (DefaultMax)"
 }
 
@@ -35,25 +62,85 @@ func (t ItemType) String() string {
 	return name
 }
 
+// ParseItemType returns the ItemType whose name is registered in
+// TypeNames as `name`. If several ItemTypes share the name, the one with
+// the lowest value is returned. The decimal representation of an ItemType
+// is accepted as well, for types that have no name
+func ParseItemType(name string) (ItemType, error) {
+	found := false
+	var t ItemType
+	for typ, n := range TypeNames {
+		if n == name && (!found || typ < t) {
+			t, found = typ, true
+		}
+	}
+	if found {
+		return t, nil
+	}
+
+	if v, err := strconv.Atoi(name); err == nil {
+		return ItemType(v), nil
+	}
+	return 0, fmt.Errorf("unknown item type %q", name)
+}
+
+// MarshalText returns the name of the ItemType, or its decimal
+// representation if it has no name
+func (t ItemType) MarshalText() ([]byte, error) {
+	if name, ok := TypeNames[t]; ok {
+		return []byte(name), nil
+	}
+	return []byte(strconv.Itoa(int(t))), nil
+}
+
+// UnmarshalText sets the ItemType from text created by MarshalText
+func (t *ItemType) UnmarshalText(text []byte) error {
+	v, err := ParseItemType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
 // LexItem defines the interface for items emitted by the Lexer
 type LexItem interface {
 	Type() ItemType
 	Pos() int
 	Line() int
+	Column() int
 	Value() string
+	Payload() interface{}
 }
 
 // Item is the struct that gets generated upon finding *something*
 type Item struct {
-	typ  ItemType
-	pos  int
-	line int
-	val  string
+	typ     ItemType
+	pos     int
+	line    int
+	col     int
+	val     string
+	payload interface{}
 }
 
 // NewItem creates a new Item
 func NewItem(t ItemType, pos int, line int, v string) Item {
-	return Item{t, pos, line, v}
+	return Item{typ: t, pos: pos, line: line, val: v}
+}
+
+// NewItemWithPayload creates a new Item with a decoded payload,
+// such as an int64 for a numeric literal
+func NewItemWithPayload(t ItemType, pos int, line int, v string, payload interface{}) Item {
+	return Item{typ: t, pos: pos, line: line, val: v, payload: payload}
+}
+
+// WithColumn returns a copy of the Item, located at column `col`. Use it
+// to keep the column of items that are created by hand, e.g. for EmitItem:
+//
+//	l.EmitItem(lex.NewItem(t, pos, line, v).WithColumn(col))
+func (l Item) WithColumn(col int) Item {
+	l.col = col
+	return l
 }
 
 // Type returns the associated ItemType
@@ -71,12 +158,103 @@ func (l Item) Line() int {
 	return l.line
 }
 
+// Column returns the column (counted in runes, starting at 1) in which
+// this occurred, or 0 if it is unknown
+func (l Item) Column() int {
+	return l.col
+}
+
 // Value returns the associated text value
 func (l Item) Value() string {
 	return l.val
 }
 
+// Payload returns the decoded value associated with the item, if any.
+// Items emitted via Emit() have no payload, and return nil
+func (l Item) Payload() interface{} {
+	return l.payload
+}
+
 // String returns the string representation of the Item
 func (l Item) String() string {
 	return fmt.Sprintf("%s (%q)", l.typ, l.val)
 }
+
+type itemJSON struct {
+	Type   ItemType `json:"type"`
+	Value  string   `json:"value"`
+	Pos    int      `json:"pos"`
+	Line   int      `json:"line"`
+	Column int      `json:"column"`
+}
+
+// MarshalJSON encodes the Item as a JSON object. The type is encoded by
+// its name, as registered in TypeNames. The payload is not encoded
+func (l Item) MarshalJSON() ([]byte, error) {
+	return json.Marshal(itemJSON{
+		Type:   l.typ,
+		Value:  l.val,
+		Pos:    l.pos,
+		Line:   l.line,
+		Column: l.col,
+	})
+}
+
+// UnmarshalJSON decodes an Item encoded by MarshalJSON
+func (l *Item) UnmarshalJSON(data []byte) error {
+	var v itemJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*l = Item{typ: v.Type, pos: v.Pos, line: v.Line, col: v.Column, val: v.Value}
+	return nil
+}
+
+// MarshalText encodes the Item as a single line of tab separated fields:
+// the type name, the line and column, the position, and the quoted value.
+//
+//	Number	1:5	4	"23"
+//
+// The payload is not encoded
+func (l Item) MarshalText() ([]byte, error) {
+	typ, err := l.typ.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s\t%d:%d\t%d\t%s", typ, l.line, l.col, l.pos, strconv.Quote(l.val))), nil
+}
+
+// UnmarshalText decodes an Item encoded by MarshalText
+func (l *Item) UnmarshalText(text []byte) error {
+	fields := strings.Split(string(text), "\t")
+	if len(fields) != 4 {
+		return fmt.Errorf("expected 4 tab separated fields, got %d", len(fields))
+	}
+
+	var item Item
+	if err := item.typ.UnmarshalText([]byte(fields[0])); err != nil {
+		return err
+	}
+	lc := strings.Split(fields[1], ":")
+	if len(lc) != 2 {
+		return fmt.Errorf("invalid line:column %q", fields[1])
+	}
+	var err error
+	if item.line, err = strconv.Atoi(lc[0]); err != nil {
+		return fmt.Errorf("invalid line:column %q", fields[1])
+	}
+	if item.col, err = strconv.Atoi(lc[1]); err != nil {
+		return fmt.Errorf("invalid line:column %q", fields[1])
+	}
+	pos, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return fmt.Errorf("invalid position %q", fields[2])
+	}
+	item.pos = pos
+	if item.val, err = strconv.Unquote(fields[3]); err != nil {
+		return fmt.Errorf("invalid value %s", fields[3])
+	}
+
+	*l = item
+	return nil
+}
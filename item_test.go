@@ -0,0 +1,168 @@
+package lex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestItemType_Values(t *testing.T) {
+	// Persisted items refer to types by number, so these must not change
+	if ItemEOF != 0 || ItemError != 1 || ItemDefaultMax != 2 {
+		t.Errorf("builtin item types were renumbered: EOF = %d, Error = %d, DefaultMax = %d", ItemEOF, ItemError, ItemDefaultMax)
+	}
+	for _, typ := range []ItemType{ItemIndent, ItemDedent, ItemNewline} {
+		if typ >= 0 || typ <= itemInclude {
+			t.Errorf("expected %s to be negative, and above the internal types, got %d", typ, typ)
+		}
+	}
+}
+
+func TestItemType_Text(t *testing.T) {
+	for _, typ := range []ItemType{ItemEOF, ItemNewline, ItemDefaultMax, ItemDefaultMax + 999} {
+		text, err := typ.MarshalText()
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", typ, err)
+			continue
+		}
+
+		var parsed ItemType
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Errorf("%d: unexpected error: %s", typ, err)
+			continue
+		}
+		if parsed != typ {
+			t.Errorf("%d: round trip through %q returned %d", typ, text, parsed)
+		}
+	}
+
+	if typ, err := ParseItemType("Error"); err != nil || typ != ItemError {
+		t.Errorf("expected ItemError, got %d (%v)", typ, err)
+	}
+	if _, err := ParseItemType("NoSuchType"); err == nil {
+		t.Errorf("expected an error for an unknown name")
+	}
+}
+
+func TestItemType_JSON(t *testing.T) {
+	buf, err := json.Marshal(map[string]ItemType{"type": ItemIndent})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(buf) != `{"type":"Indent"}` {
+		t.Errorf("expected the ItemType to be encoded by name, got %s", buf)
+	}
+
+	var v struct{ Type ItemType }
+	if err := json.Unmarshal([]byte(`{"Type":"Dedent"}`), &v); err != nil || v.Type != ItemDedent {
+		t.Errorf("expected ItemDedent, got %d (%v)", v.Type, err)
+	}
+}
+
+func TestItem_Column(t *testing.T) {
+	const input = "1 + 23  +\n 4"
+	tlc := &testLexCtx{}
+	lexers := map[string]Lexer{
+		"StringLexer": NewStringLexer(input, tlc.lexStart),
+		"ReaderLexer": NewReaderLexer(strings.NewReader(input), tlc.lexStart),
+	}
+
+	expected := []string{"1:1", "1:2", "1:3", "1:4", "1:5", "1:7", "1:9", "1:10", "2:1", "2:2", "2:3"}
+	for name, l := range lexers {
+		go l.Run()
+		var got []string
+		for item := l.NextItem(); item != nil; item = l.NextItem() {
+			got = append(got, fmt.Sprintf("%d:%d", item.Line(), item.Column()))
+		}
+		if strings.Join(got, " ") != strings.Join(expected, " ") {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestItem_WithColumn(t *testing.T) {
+	l := NewStringLexer("", nil, WithBufferSize(1))
+	l.EmitItem(NewItemWithPayload(ItemNumber, 4, 2, "12", int64(12)).WithColumn(3))
+	item := l.NextItem()
+	if item.Line() != 2 || item.Column() != 3 || item.Payload() != int64(12) {
+		t.Errorf("expected line 2, column 3 and the payload, got %#v", item)
+	}
+}
+
+func TestItem_UnmarshalText_Errors(t *testing.T) {
+	for _, lc := range []string{"1", "1:2:3", "1:2x", "x:2", "1:", ":2", "1 :2"} {
+		var item Item
+		err := item.UnmarshalText([]byte("EOF\t" + lc + "\t0\t\"\""))
+		if expected := fmt.Sprintf("invalid line:column %q", lc); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected error %q, got %v", lc, expected, err)
+		}
+	}
+}
+
+func TestItem_JSON(t *testing.T) {
+	item := NewItem(ItemNewline, 10, 2, "\n")
+	item.col = 5
+
+	buf, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(buf) != `{"type":"Newline","value":"\n","pos":10,"line":2,"column":5}` {
+		t.Errorf("unexpected JSON: %s", buf)
+	}
+
+	var decoded Item
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if decoded != item {
+		t.Errorf("expected %#v, got %#v", item, decoded)
+	}
+}
+
+func TestWriteItems(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer("1 +\t\n 2", tlc.lexStart)
+	go l.Run()
+
+	var buf bytes.Buffer
+	if err := WriteItems(&buf, l); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// ItemWhitespace has no name, so it is written as a number
+	expected := fmt.Sprintf("%s\t1:1\t0\t\"1\"\n%d\t1:2\t1\t\" \"\n", ItemNumber, ItemWhitespace)
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	items, err := ReadItems(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var again bytes.Buffer
+	for _, item := range items {
+		text, _ := item.MarshalText()
+		again.Write(text)
+		again.WriteByte('\n')
+	}
+	if again.String() != buf.String() {
+		t.Errorf("round trip failed:\n%s\n---\n%s", buf.String(), again.String())
+	}
+	if last := items[len(items)-1]; last.Type() != ItemEOF || last.Line() != 2 || last.Column() != 3 {
+		t.Errorf("unexpected last item %#v", last)
+	}
+
+	large := NewItem(ItemNumber, 0, 1, strings.Repeat("x", 100000))
+	text, _ := large.MarshalText()
+	if items, err := ReadItems(bytes.NewReader(text)); err != nil || len(items) != 1 || items[0].Value() != large.Value() {
+		t.Errorf("failed to read back a large item: %v", err)
+	}
+
+	if _, err := ReadItems(strings.NewReader("EOF\t1:1\t0\n")); err == nil || err.Error() != "line 1: expected 4 tab separated fields, got 3" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package lex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// WriteItems writes the items from `src` to `w`, one per line, in the
+// format produced by Item.MarshalText, until `src` is exhausted. If
+// `src` is a Lexer, it must be running
+func WriteItems(w io.Writer, src ItemSource) error {
+	bw := bufio.NewWriter(w)
+	for item := src.NextItem(); item != nil; item = src.NextItem() {
+		i := Item{
+			typ:  item.Type(),
+			pos:  item.Pos(),
+			line: item.Line(),
+			col:  item.Column(),
+			val:  item.Value(),
+		}
+		text, err := i.MarshalText()
+		if err != nil {
+			return err
+		}
+		bw.Write(text)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// ReadItems reads the items written by WriteItems. Empty lines are
+// skipped. There is no limit on the length of a line, so items with
+// large values can be read back
+func ReadItems(r io.Reader) ([]Item, error) {
+	var items []Item
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			var item Item
+			if err := item.UnmarshalText(line); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineno, err)
+			}
+			items = append(items, item)
+		}
+
+		if err == io.EOF {
+			return items, nil
+		}
+	}
+}
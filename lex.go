@@ -31,6 +31,17 @@ import (
 //     l := lex.NewStringReader(src, f.lexFoo)
 //     l.Run()
 //     
+// Alternatively, you can store the state in the lexer itself using
+// SetContext (or the WithContext option), and retrieve it from within
+// a regular function via Context():
+//
+//     func lexFoo(l lex.Lexer) lex.LexFn {
+//       f := l.Context().(*Foo)
+//       ...
+//     }
+//
+//     l := lex.NewStringLexer(src, lexFoo, lex.WithContext(&Foo{}))
+//
 type LexFn func(Lexer) LexFn
 
 // EOF is used to signal that we have reached EOF
@@ -44,27 +55,48 @@ type Lexer interface {
 	Next() rune
 	Peek() rune
 	Backup()
+	Line() int
+	Cursor() int
+	Column() int
 	PeekString(string) bool
 	AcceptAny(string) bool
 	AcceptString(string) bool
 	AcceptRun(string) bool
 	AcceptRunFunc(func(r rune) bool) bool
 	AcceptRunExcept(string) bool
+	AcceptAnySet(*RuneSet) bool
+	AcceptRunSet(*RuneSet) bool
 	EmitErrorf(string, ...interface{}) LexFn
 	Emit(ItemType)
+	Ignore()
+	EmitPayload(ItemType, interface{})
+	EmitValue(ItemType, string)
+	EmitItem(LexItem)
 	Items() chan LexItem
 	BufferString() string
 	NextItem() LexItem
+	SetContext(interface{})
+	Context() interface{}
 }
 
 // LexRun starts lexing using Lexer l, and a context Lexer ctx. "Context" in
 // this case can be thought as the concret lexer, and l is the parent class.
 // This is a utility function to be called from concrete Lexer types
 func LexRun(l Lexer) {
-	for fn := l.GetEntryPoint(); fn != nil; {
+	o, ok := l.(outputter)
+	if !ok {
+		for fn := l.GetEntryPoint(); fn != nil; {
+			fn = fn(l)
+		}
+		close(l.Items())
+		return
+	}
+
+	out := o.output()
+	for fn := l.GetEntryPoint(); fn != nil && !out.aborted(); {
 		fn = fn(l)
 	}
-	close(l.Items())
+	out.close()
 }
 
 // This method moves the cursor 1 rune if the rune is contained in the given
@@ -77,6 +109,9 @@ func AcceptAny(l Lexer, valid string) bool {
 	return false
 }
 
+// AcceptRunFunc takes a function, and moves the cursor forward as long as
+// the function returns true. The cursor never moves past EOF.
+// This is a utility function to be called from concrete Lexer types
 func AcceptRunFunc(l Lexer, fn func(rune) bool) bool {
 	guard := Mark("lex.AcceptRunFunc")
 	defer guard()
@@ -85,7 +120,7 @@ func AcceptRunFunc(l Lexer, fn func(rune) bool) bool {
 	for {
 		n := l.Next()
 		Trace("%d: n -> %q\n", count, n)
-		if !fn(n) {
+		if n == EOF || !fn(n) {
 			break
 		}
 
@@ -129,7 +164,7 @@ func AcceptString(l Lexer, word string, rewind bool) (ok bool) {
 				l.Backup()
 			}
 		}
-		Trace("AcceptString returning %s\n", ok)
+		Trace("AcceptString returning %t\n", ok)
 	}()
 
 	for pos := 0; pos < len(word); {
@@ -142,7 +177,7 @@ func AcceptString(l Lexer, word string, rewind bool) (ok bool) {
 			n = l.Next()
 		}
 		i++
-		Trace("r (%q) == n (%q) %s ? \n", r, n, r == n)
+		Trace("r (%q) == n (%q) %t ? \n", r, n, r == n)
 		if r != n {
 			rewind = true
 			ok = false
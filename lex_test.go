@@ -2,6 +2,7 @@ package lex
 
 import (
 	"bytes"
+	"strconv"
 	"testing"
 )
 
@@ -124,18 +125,26 @@ func TestReaderLexer(t *testing.T) {
 }
 
 func verify(t *testing.T, l Lexer) {
-	expectedItems := []Item{
-		NewItem(ItemNumber, 0, 1, "1"),
-		NewItem(ItemWhitespace, 1, 1, " "),
-		NewItem(ItemOperator, 2, 1, "+"),
-		NewItem(ItemWhitespace, 3, 1, "\n"),
-		NewItem(ItemWhitespace, 4, 2, " "),
-		NewItem(ItemNumber, 5, 2, "2"),
-		NewItem(ItemEOF, 6, 2, ""),
+	var items []LexItem
+	for item := range l.Items() {
+		items = append(items, item)
 	}
+	verifyItems(t, testItems, items)
+}
 
-	i := 0
-	for item := range l.Items() {
+// testItems are the items that testLexCtx produces for "1 +\n 2"
+var testItems = []Item{
+	NewItem(ItemNumber, 0, 1, "1"),
+	NewItem(ItemWhitespace, 1, 1, " "),
+	NewItem(ItemOperator, 2, 1, "+"),
+	NewItem(ItemWhitespace, 3, 1, "\n"),
+	NewItem(ItemWhitespace, 4, 2, " "),
+	NewItem(ItemNumber, 5, 2, "2"),
+	NewItem(ItemEOF, 6, 2, ""),
+}
+
+func verifyItems(t *testing.T, expectedItems []Item, items []LexItem) {
+	for i, item := range items {
 		t.Logf("----")
 		if i >= len(expectedItems) {
 			t.Fatalf("expected %d items, received more than that (%#v)", len(expectedItems), item)
@@ -157,10 +166,119 @@ func verify(t *testing.T, l Lexer) {
 		if expected.Value() != item.Value() {
 			t.Errorf("Value did not match: Expected '%s', got '%s'", expected.Value(), item.Value())
 		}
-		i++
+
+		if expected.Payload() != item.Payload() {
+			t.Errorf("Payload did not match: Expected %#v, got %#v", expected.Payload(), item.Payload())
+		}
+	}
+
+	if len(items) != len(expectedItems) {
+		t.Errorf("Expected %d items, only got %d", len(expectedItems), len(items))
 	}
+}
+
+func lexPayload(l Lexer) LexFn {
+	if l.Peek() == EOF {
+		l.Emit(ItemEOF)
+		return nil
+	}
+	if !l.AcceptRun("0123456789") {
+		return l.EmitErrorf("Expected number")
+	}
+	v, err := strconv.ParseInt(l.BufferString(), 10, 64)
+	if err != nil {
+		return l.EmitErrorf("Invalid number: %s", err)
+	}
+	l.EmitPayload(ItemNumber, v)
+	if l.AcceptRun(" ") {
+		l.Emit(ItemWhitespace)
+	}
+	return lexPayload
+}
+
+func TestLexer_EmitPayload(t *testing.T) {
+	for _, l := range []Lexer{
+		NewStringLexer("12 345", lexPayload),
+		NewReaderLexer(bytes.NewBufferString("12 345"), lexPayload),
+	} {
+		go l.Run()
+
+		var payloads []interface{}
+		for item := range l.Items() {
+			if item.Type() == ItemError {
+				t.Fatalf("unexpected error: %s", item.Value())
+			}
+			if item.Type() == ItemNumber {
+				payloads = append(payloads, item.Payload())
+				continue
+			}
+			if item.Payload() != nil {
+				t.Errorf("expected no payload for %s, got %#v", item, item.Payload())
+			}
+		}
 
-	if i != len(expectedItems) {
-		t.Errorf("Expected %d items, only got %d", len(expectedItems), i)
+		if len(payloads) != 2 || payloads[0] != int64(12) || payloads[1] != int64(345) {
+			t.Errorf("expected payloads [12 345], got %#v", payloads)
+		}
+	}
+}
+
+func TestLexer_EmitValue(t *testing.T) {
+	for _, l := range []Lexer{
+		NewStringLexer("12\n34", nil, WithBufferSize(4)),
+		NewReaderLexer(bytes.NewBufferString("12\n34"), nil, WithBufferSize(4)),
+	} {
+		l.AcceptRun("0123456789")
+		l.EmitValue(ItemOperator, ";")
+		l.Emit(ItemNumber)
+		l.AcceptString("\n")
+		l.EmitItem(NewItem(ItemOperator, 100, 100, "synthetic"))
+		l.AcceptRun("0123456789")
+		l.Emit(ItemNumber)
+
+		expected := []Item{
+			NewItem(ItemOperator, 2, 1, ";"),
+			NewItem(ItemNumber, 0, 1, "12"),
+			NewItem(ItemOperator, 100, 100, "synthetic"),
+			NewItem(ItemNumber, 2, 1, "\n34"),
+		}
+		items := make([]LexItem, len(expected))
+		for i := range items {
+			items[i] = l.NextItem()
+		}
+		verifyItems(t, expected, items)
+	}
+}
+
+func TestStringLexer_AdvanceCursor(t *testing.T) {
+	l := NewStringLexer("a\nb\nc", nil)
+	l.AdvanceCursor(4)
+	if l.Line() != 3 || l.Current() != 'c' {
+		t.Errorf("expected line 3 at 'c', got line %d at %q", l.Line(), l.Current())
+	}
+	l.AdvanceCursor(-2)
+	if l.Line() != 2 || l.Current() != 'b' {
+		t.Errorf("expected line 2 at 'b', got line %d at %q", l.Line(), l.Current())
+	}
+	l.AdvanceCursor(1)
+	if l.Line() != 2 || l.Column() != 2 {
+		t.Errorf("expected line 2, column 2, got line %d, column %d", l.Line(), l.Column())
+	}
+}
+
+func TestLexer_BackupAtStart(t *testing.T) {
+	for _, l := range []Lexer{
+		NewStringLexer("ab", nil, WithBufferSize(2)),
+		NewReaderLexer(bytes.NewBufferString("ab"), nil, WithBufferSize(2)),
+	} {
+		l.Backup()
+		if r := l.Next(); r != 'a' {
+			t.Errorf("%T: expected 'a' after backing up at the start, got %q", l, r)
+		}
+		l.Emit(ItemOperator)
+		l.Backup()
+		if r := l.Next(); r != 'b' {
+			t.Errorf("%T: expected 'b' after backing up past an Emit, got %q", l, r)
+		}
 	}
 }
@@ -0,0 +1,73 @@
+package lextest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Backend creates a Lexer of a particular implementation, which lexes
+// `input` using `fn`
+type Backend struct {
+	Name string
+	New  func(input []byte, fn lex.LexFn) lex.Lexer
+}
+
+// Backends lists the Lexer implementations that Check and Run compare.
+// The first one is the reference, whose output is written to the golden
+// files. Append to it to cover your own implementations
+var Backends = []Backend{
+	{
+		Name: "StringLexer",
+		New: func(input []byte, fn lex.LexFn) lex.Lexer {
+			return lex.New(lex.StringSource(string(input)), fn)
+		},
+	},
+	{
+		Name: "ReaderLexer",
+		New: func(input []byte, fn lex.LexFn) lex.Lexer {
+			return lex.New(lex.ReaderSource(bytes.NewReader(input)), fn)
+		},
+	},
+}
+
+// Divergence describes the point at which two backends produced
+// different items for the same input
+type Divergence struct {
+	Input     []byte
+	Reference string
+	Backend   string
+	Diffs     []string
+}
+
+// Error returns the string representation of the Divergence
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("%s and %s disagree on %q (- %s, + %s):\n%s", d.Reference, d.Backend, d.Input, d.Reference, d.Backend, strings.Join(d.Diffs, "\n"))
+}
+
+// Check lexes `input` using `fn` with each of the Backends, and returns a
+// *Divergence if any of them produced items that differ from the first
+// one in type, position, line, column or value. The dump of the first
+// backend is returned as well
+func Check(fn lex.LexFn, input []byte) ([]string, error) {
+	var reference []string
+	for i, b := range Backends {
+		dump := Dump(b.New(input, fn))
+		if i == 0 {
+			reference = dump
+			continue
+		}
+
+		if diffs := Diff(reference, dump); len(diffs) > 0 {
+			return reference, &Divergence{
+				Input:     input,
+				Reference: Backends[0].Name,
+				Backend:   b.Name,
+				Diffs:     diffs,
+			}
+		}
+	}
+	return reference, nil
+}
@@ -0,0 +1,78 @@
+package lextest
+
+import (
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/lex/lextest/internal/grammars"
+)
+
+// checkInputs are run by TestCheck, and seed the corpus of FuzzCheck
+var checkInputs = []string{
+	"",
+	"\n",
+	"1.5 \"a\\\"b\" # comment\nfoo:: <<= <= < !=\n",
+	"\n\n  x\n",
+	"@a @",
+	"%1%",
+	"12\n$",
+	"\"unterminated\n",
+	"λ\n\"é\"\n3.",
+	"/* a\nb */ 0x1F 'x' \"\\u00e9\" // c\n1e",
+	"a\n  b\n\tc\n\n d\ne",
+	"ab",
+	"abc de\nf\n",
+}
+
+func TestCheck(t *testing.T) {
+	for name, fn := range grammars.All {
+		for _, input := range checkInputs {
+			if _, err := Check(fn, []byte(input)); err != nil {
+				t.Errorf("%s: %s", name, err)
+			}
+		}
+	}
+}
+
+// FuzzCheck runs each of the grammars over the fuzzed input with all of
+// the Backends, and fails if they disagree:
+//
+//	go test -run XXX -fuzz FuzzCheck
+//
+// Only valid UTF-8 is checked: ReaderLexer decodes its input into runes,
+// so it replaces invalid bytes with utf8.RuneError, while StringLexer
+// keeps them as they are
+func FuzzCheck(f *testing.F) {
+	for _, input := range checkInputs {
+		f.Add([]byte(input))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !utf8.Valid(data) {
+			t.Skip("invalid UTF-8")
+		}
+		for name, fn := range grammars.All {
+			if _, err := Check(fn, data); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+	})
+}
+
+func TestCheck_Random(t *testing.T) {
+	const alphabet = "0123456789.x \t\n\"'\\/*#ab:<=!@%$λé"
+	runes := []rune(alphabet)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		input := make([]rune, rng.Intn(20))
+		for j := range input {
+			input[j] = runes[rng.Intn(len(runes))]
+		}
+		for name, fn := range grammars.All {
+			if _, err := Check(fn, []byte(string(input))); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package grammars contains the LexFns that the differential tests and
+// the fuzz target of the lextest package run through the Backends. Each
+// of them exercises a different part of the Lexer interface
+package grammars
+
+import (
+	"unicode"
+
+	"github.com/lestrrat-go/lex"
+	"github.com/lestrrat-go/lex/scan"
+)
+
+const (
+	itemNumber lex.ItemType = lex.ItemDefaultMax + 1 + iota
+	itemString
+	itemIdent
+	itemSpace
+	itemNewline
+	itemOperator
+	itemMarker
+	itemComment
+	itemOther
+)
+
+// All maps the name of each grammar to its entry point
+var All = map[string]lex.LexFn{
+	"primitives": lexPrimitives,
+	"scan":       lexScan,
+	"indent":     lexIndentStart,
+	"backup":     lexBackup,
+}
+
+// lexPrimitives is a grammar that exercises most of the methods in the
+// Lexer interface, including multi-rune Backup, EOF handling and errors
+func lexPrimitives(l lex.Lexer) lex.LexFn {
+	switch r := l.Next(); {
+	case r == lex.EOF:
+		l.Emit(lex.ItemEOF)
+		return nil
+	case r >= '0' && r <= '9':
+		l.AcceptRun("0123456789")
+		if l.AcceptString(".") && !l.AcceptRun("0123456789") {
+			l.Backup()
+		}
+		l.Emit(itemNumber)
+	case r == '"':
+		for {
+			switch l.Next() {
+			case '\\':
+				l.Next()
+				continue
+			case '"':
+				l.Emit(itemString)
+				return lexPrimitives
+			case lex.EOF:
+				return l.EmitErrorf("unterminated string")
+			}
+		}
+	case r == '#':
+		l.AcceptRunExcept("\n")
+		l.Ignore()
+	case unicode.IsLetter(r):
+		l.AcceptRunFunc(unicode.IsLetter)
+		if l.PeekString("::") {
+			l.AcceptString("::")
+		}
+		l.Emit(itemIdent)
+	case r == ' ' || r == '\t':
+		l.AcceptRun(" \t")
+		l.Emit(itemSpace)
+	case r == '\n':
+		l.Emit(itemNewline)
+	case r == '<':
+		l.Backup()
+		if !l.AcceptString("<<=") && !l.AcceptString("<=") {
+			l.AcceptString("<")
+		}
+		l.Emit(itemOperator)
+	case r == '!':
+		l.AcceptAny("=!")
+		l.Emit(itemOperator)
+	case r == '@':
+		l.Backup()
+		if l.Current() != '@' {
+			return l.EmitErrorf("expected '@' at the cursor")
+		}
+		l.Next()
+		l.Emit(itemOperator)
+	case r == '%':
+		l.EmitValue(itemMarker, "%")
+		l.Ignore()
+	case r == '$':
+		return l.EmitErrorf("unexpected %q at line %d", r, l.Line())
+	default:
+		l.Emit(itemOther)
+	}
+	return lexPrimitives
+}
+
+// lexScan lexes Go-like source using the helpers in the scan package
+func lexScan(l lex.Lexer) lex.LexFn {
+	if l.Peek() == lex.EOF {
+		l.Emit(lex.ItemEOF)
+		return nil
+	}
+
+	if l.AcceptRun(" \t\n") {
+		l.Ignore()
+		return lexScan
+	}
+
+	if scan.AcceptLineComment(l, "//") {
+		l.Emit(itemComment)
+		return lexScan
+	}
+
+	if ok, err := scan.AcceptBlockComment(l, "/*", "*/", false); ok {
+		if err != nil {
+			return l.EmitErrorf("%s", err)
+		}
+		l.Emit(itemComment)
+		return lexScan
+	}
+
+	if scan.AcceptIdentifier(l, scan.GoIdent) {
+		l.Emit(itemIdent)
+		return lexScan
+	}
+
+	kind, err := scan.AcceptNumber(l, scan.GoNumber)
+	if err != nil {
+		return l.EmitErrorf("%s", err)
+	}
+	if kind != scan.NumberNone {
+		l.Emit(itemNumber)
+		return lexScan
+	}
+
+	for _, spec := range []scan.QuoteSpec{scan.GoString, scan.GoRune} {
+		v, ok, err := scan.AcceptQuoted(l, spec)
+		if err != nil {
+			return l.EmitErrorf("%s", err)
+		}
+		if ok {
+			l.EmitPayload(itemString, v)
+			return lexScan
+		}
+	}
+
+	l.Next()
+	l.Emit(itemOther)
+	return lexScan
+}
+
+// lexIndentStart lexes an indentation sensitive grammar using
+// lex.IndentTracker
+func lexIndentStart(l lex.Lexer) lex.LexFn {
+	l.SetContext(lex.NewIndentTracker(4))
+	return lexIndentLine
+}
+
+func lexIndentLine(l lex.Lexer) lex.LexFn {
+	if err := l.Context().(*lex.IndentTracker).LineStart(l); err != nil {
+		return l.EmitErrorf("%s", err)
+	}
+	return lexIndentBody
+}
+
+func lexIndentBody(l lex.Lexer) lex.LexFn {
+	t := l.Context().(*lex.IndentTracker)
+	switch {
+	case l.Peek() == lex.EOF:
+		t.Finish(l)
+		l.Emit(lex.ItemEOF)
+		return nil
+	case t.Newline(l):
+		return lexIndentLine
+	case l.AcceptRun(" \t"):
+		l.Ignore()
+	case l.AcceptRunExcept(" \t\n"):
+		l.Emit(itemIdent)
+	}
+	return lexIndentBody
+}
+
+// lexBackup backs up more than it has read: at the beginning of the input,
+// and right after each Emit. Backup must never move the cursor before
+// the end of the last item
+func lexBackup(l lex.Lexer) lex.LexFn {
+	l.Backup()
+	switch r := l.Next(); {
+	case r == lex.EOF:
+		l.Backup()
+		l.Backup()
+		l.Emit(lex.ItemEOF)
+		return nil
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		l.AcceptRunFunc(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+		// give back the last rune, unless the token is a single rune
+		if l.Backup(); l.BufferString() == "" {
+			l.Next()
+		}
+		l.Emit(itemIdent)
+	case r == '\n':
+		l.Emit(itemNewline)
+	default:
+		l.Emit(itemOther)
+	}
+	l.Backup()
+	l.Backup()
+	return lexBackup
+}
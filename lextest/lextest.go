@@ -0,0 +1,165 @@
+/*
+Package lextest runs golden file tests for LexFn grammars. Each input
+file is lexed by each of the Backends (StringLexer and ReaderLexer by
+default), and the resulting items are compared with each other, and with
+the contents of a golden file:
+
+	func TestGrammar(t *testing.T) {
+	  lextest.Run(t, lexStart, "testdata")
+	}
+
+For each file named "foo.input" in the directory, the golden file is
+"foo.golden". It contains one item per line, in the format written by
+lex.WriteItems. To create or overwrite the golden files with the current
+output, set Update, or define an -update flag in your test package, which
+Run picks up:
+
+	var update = flag.Bool("update", false, "update the golden files")
+
+	go test -run TestGrammar -update
+
+This package does not register any flags itself, so that it doesn't
+clash with the ones defined by your tests
+*/
+package lextest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Update makes Run create or overwrite the golden files with the current
+// output, instead of comparing against them. Run also does so if the test
+// binary defines a boolean flag named "update", and it is set
+var Update bool
+
+func updating() bool {
+	if Update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	v, ok := getter.Get().(bool)
+	return ok && v
+}
+
+// maxDiffs is the maximum number of differences reported per file
+const maxDiffs = 10
+
+// Run lexes each "*.input" file in `dir` using `fn`, starting with a
+// subtest per file. The test fails if the Backends disagree, or if the
+// items don't match the golden file
+func Run(t *testing.T, fn lex.LexFn, dir string) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.input"))
+	if err != nil {
+		t.Fatalf("failed to list input files: %s", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no input files found in %s", dir)
+	}
+
+	for _, input := range inputs {
+		input := input
+		name := strings.TrimSuffix(filepath.Base(input), ".input")
+		t.Run(name, func(t *testing.T) {
+			runFile(t, fn, input, strings.TrimSuffix(input, ".input")+".golden")
+		})
+	}
+}
+
+func runFile(t *testing.T, fn lex.LexFn, input, golden string) {
+	src, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatalf("failed to read input: %s", err)
+	}
+
+	dump, err := Check(fn, src)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if updating() {
+		if err := ioutil.WriteFile(golden, []byte(strings.Join(dump, "")), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %s", err)
+		}
+		return
+	}
+
+	buf, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %s", err)
+	}
+	expected := strings.SplitAfter(string(buf), "\n")
+	if expected[len(expected)-1] == "" {
+		expected = expected[:len(expected)-1]
+	}
+
+	if diffs := Diff(expected, dump); len(diffs) > 0 {
+		t.Errorf("items for %s do not match %s (- expected, + got):\n%s", input, golden, strings.Join(diffs, "\n"))
+	}
+}
+
+// Dump runs the lexer, and returns the items it emitted, one per line,
+// in the format written by lex.WriteItems. Each line includes the
+// trailing newline
+func Dump(l lex.Lexer) []string {
+	go l.Run()
+
+	var buf bytes.Buffer
+	lex.WriteItems(&buf, l)
+	lines := strings.SplitAfter(buf.String(), "\n")
+	return lines[:len(lines)-1]
+}
+
+// Diff compares two dumps item by item, and describes the differences.
+// Each line of a dump contains the line, column and position of the item,
+// so the descriptions point to where the dumps diverge. At most 10
+// differences are described. An empty result means that the dumps match
+func Diff(expected, got []string) []string {
+	var diffs []string
+	n := len(expected)
+	if len(got) > n {
+		n = len(got)
+	}
+
+	for i := 0; i < n; i++ {
+		var e, g string
+		if i < len(expected) {
+			e = strings.TrimSuffix(expected[i], "\n")
+		}
+		if i < len(got) {
+			g = strings.TrimSuffix(got[i], "\n")
+		}
+		if e == g {
+			continue
+		}
+
+		if len(diffs) == maxDiffs {
+			diffs = append(diffs, "  ...")
+			break
+		}
+
+		diff := fmt.Sprintf("  item %d:", i)
+		if i < len(expected) {
+			diff += "\n    - " + e
+		}
+		if i < len(got) {
+			diff += "\n    + " + g
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
@@ -0,0 +1,71 @@
+package lextest_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/lex"
+	"github.com/lestrrat-go/lex/lextest"
+)
+
+// update is defined by the test package, the way users of lextest would
+// define it, to check that there is no clash with lextest itself
+var update = flag.Bool("update", false, "update the golden files")
+
+const (
+	ItemNumber lex.ItemType = lex.ItemDefaultMax + 1 + iota
+	ItemOperator
+	ItemWhitespace
+	ItemOther
+)
+
+func init() {
+	lex.TypeNames[ItemNumber] = "Number"
+	lex.TypeNames[ItemOperator] = "Operator"
+	lex.TypeNames[ItemWhitespace] = "Whitespace"
+	lex.TypeNames[ItemOther] = "Other"
+}
+
+func lexStart(l lex.Lexer) lex.LexFn {
+	switch r := l.Next(); {
+	case r == lex.EOF:
+		l.Emit(lex.ItemEOF)
+		return nil
+	case r >= '0' && r <= '9':
+		l.AcceptRun("0123456789")
+		l.Emit(ItemNumber)
+	case strings.ContainsRune("+-*/()", r):
+		l.Emit(ItemOperator)
+	case r == ' ' || r == '\n':
+		l.AcceptRun(" \n")
+		l.Emit(ItemWhitespace)
+	default:
+		l.AcceptRunFunc(func(r rune) bool { return !strings.ContainsRune(" \n0123456789+-*/()", r) })
+		l.Emit(ItemOther)
+	}
+	return lexStart
+}
+
+func TestRun(t *testing.T) {
+	lextest.Run(t, lexStart, "testdata")
+}
+
+func TestDiff(t *testing.T) {
+	expected := []string{"Number\t1:1\t0\t\"1\"\n", "EOF\t1:2\t1\t\"\"\n"}
+	got := []string{"Number\t1:1\t0\t\"12\"\n", "EOF\t1:3\t2\t\"\"\n", "EOF\t1:3\t2\t\"\"\n"}
+
+	diffs := lextest.Diff(expected, got)
+	want := []string{
+		"  item 0:\n    - Number\t1:1\t0\t\"1\"\n    + Number\t1:1\t0\t\"12\"",
+		"  item 1:\n    - EOF\t1:2\t1\t\"\"\n    + EOF\t1:3\t2\t\"\"",
+		"  item 2:\n    + EOF\t1:3\t2\t\"\"",
+	}
+	if strings.Join(diffs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diff:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	if diffs := lextest.Diff(expected, expected); len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+}
@@ -0,0 +1,75 @@
+package lex
+
+// Option configures a lexer upon construction. Options are created
+// using the With* functions in this package
+type Option func(*config)
+
+// config holds the values that were set using Options
+type config struct {
+	bufferSize int
+	batchSize  int
+	sink       func(LexItem) error
+	filename   string
+	context    interface{}
+}
+
+func newConfig(options []Option) *config {
+	c := &config{
+		bufferSize: 1,
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithBufferSize specifies the capacity of the channel returned by
+// Items() (or Batches(), if batching is enabled). The default is 1.
+// A value of 0 makes the channel unbuffered
+func WithBufferSize(n int) Option {
+	return func(c *config) {
+		if n < 0 {
+			n = 0
+		}
+		c.bufferSize = n
+	}
+}
+
+// WithBatchSize turns on batched delivery of items. See
+// StringLexer.SetBatchSize for details
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithItemSink specifies a callback that receives the items directly,
+// bypassing the channels altogether. If the callback returns an error,
+// lexing is aborted, and the error is available via the lexer's Err()
+// method. Items() is still closed when lexing ends, but no items are
+// sent through it.
+//
+// The error is checked in between LexFn calls, so the LexFn that emitted
+// the failing item runs to completion. Any items that it emits after the
+// failure are dropped, without being passed to the callback
+func WithItemSink(fn func(LexItem) error) Option {
+	return func(c *config) {
+		c.sink = fn
+	}
+}
+
+// WithFilename specifies the name of the input, which is available via
+// the lexer's Filename() method
+func WithFilename(name string) Option {
+	return func(c *config) {
+		c.filename = name
+	}
+}
+
+// WithContext specifies the initial value for the lexer's user context.
+// See Lexer.SetContext
+func WithContext(v interface{}) Option {
+	return func(c *config) {
+		c.context = v
+	}
+}
@@ -0,0 +1,121 @@
+package lex
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWithItemSink(t *testing.T) {
+	tlc := &testLexCtx{}
+	var items []LexItem
+	sink := func(item LexItem) error {
+		items = append(items, item)
+		return nil
+	}
+	NewStringLexer("1 +\n 2", tlc.lexStart, WithItemSink(sink)).Run()
+	verifyItems(t, testItems, items)
+
+	items = nil
+	NewReaderLexer(bytes.NewBufferString("1 +\n 2"), tlc.lexStart, WithItemSink(sink)).Run()
+	verifyItems(t, testItems, items)
+}
+
+func TestWithItemSink_Abort(t *testing.T) {
+	tlc := &testLexCtx{}
+	stop := errors.New("stop")
+	count, calls := 0, 0
+	var counted func(LexFn) LexFn
+	counted = func(fn LexFn) LexFn {
+		if fn == nil {
+			return nil
+		}
+		return func(l Lexer) LexFn {
+			calls++
+			return counted(fn(l))
+		}
+	}
+	l := NewStringLexer("1 + 2 + 3", counted(tlc.lexStart), WithItemSink(func(LexItem) error {
+		count++
+		if count == 2 {
+			return stop
+		}
+		return nil
+	}))
+	l.Run()
+
+	if count != 2 {
+		t.Errorf("expected the sink to receive 2 items, got %d", count)
+	}
+	// Items after the failure must not just be dropped: lexing must stop
+	// right after the second item
+	if l.Cursor() != 2 {
+		t.Errorf("expected lexing to stop at position 2, got %d", l.Cursor())
+	}
+	aborted := calls
+	calls = 0
+	NewStringLexer("1 + 2 + 3", counted(tlc.lexStart), WithItemSink(func(LexItem) error { return nil })).Run()
+	if aborted >= calls {
+		t.Errorf("expected fewer LexFn calls than the %d needed for the whole input, got %d", calls, aborted)
+	}
+	if l.Err() != stop {
+		t.Errorf("expected Err() to return the sink error, got %v", l.Err())
+	}
+	if _, ok := <-l.Items(); ok {
+		t.Errorf("expected items channel to be closed")
+	}
+}
+
+func TestWithBufferSize(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer("1 +\n 2", tlc.lexStart, WithBufferSize(16))
+	l.Run()
+	verify(t, l)
+
+	l = NewStringLexer("1 +\n 2", tlc.lexStart, WithBufferSize(16), WithBatchSize(4))
+	l.Run()
+	verifyItems(t, testItems, collect(l))
+}
+
+type countCtx struct {
+	numbers int
+}
+
+func lexCountNumbers(l Lexer) LexFn {
+	switch r := l.Next(); {
+	case r == EOF:
+		l.Emit(ItemEOF)
+		return nil
+	case r >= '0' && r <= '9':
+		l.AcceptRun("0123456789")
+		l.Context().(*countCtx).numbers++
+		l.Emit(ItemNumber)
+	default:
+		l.Emit(ItemWhitespace)
+	}
+	return lexCountNumbers
+}
+
+func TestWithContext(t *testing.T) {
+	ctx := &countCtx{}
+	l := NewStringLexer("1 23 456", lexCountNumbers, WithContext(ctx))
+	go l.Run()
+	for range l.Items() {
+	}
+	if ctx.numbers != 3 {
+		t.Errorf("expected 3 numbers, got %d", ctx.numbers)
+	}
+
+	ctx = &countCtx{}
+	rl := NewReaderLexer(bytes.NewBufferString("1 23 456"), lexCountNumbers)
+	rl.SetContext(ctx)
+	if rl.Context() != ctx {
+		t.Fatalf("expected Context() to return the value passed to SetContext()")
+	}
+	go rl.Run()
+	for range rl.Items() {
+	}
+	if ctx.numbers != 3 {
+		t.Errorf("expected 3 numbers, got %d", ctx.numbers)
+	}
+}
@@ -0,0 +1,114 @@
+package lex
+
+// output takes care of delivering the items emitted by a lexer to its
+// consumer. By default each item is sent through the items channel as is,
+// but when a batch size is set the items are accumulated and sent through
+// the batches channel in chunks, which cuts down the number of channel
+// operations for token-dense inputs. If a sink is set, the channels are
+// bypassed altogether.
+type output struct {
+	items      chan LexItem
+	bufferSize int
+	batches    chan []LexItem
+	batchSize  int
+	batch      []LexItem
+	sink       func(LexItem) error
+	err        error
+
+	// pending holds the items from the last batch that have not been
+	// handed out by next() yet. It is only touched by the consumer
+	pending []LexItem
+}
+
+func newOutput(c *config) output {
+	o := output{
+		items:      make(chan LexItem, c.bufferSize),
+		bufferSize: c.bufferSize,
+		sink:       c.sink,
+	}
+	o.setBatchSize(c.batchSize)
+	return o
+}
+
+// setBatchSize switches the output to batched delivery. Sizes less than
+// 2 turn batching off
+func (o *output) setBatchSize(n int) {
+	if n < 2 {
+		o.batchSize = 0
+		o.batches = nil
+		o.batch = nil
+		return
+	}
+	o.batchSize = n
+	o.batches = make(chan []LexItem, o.bufferSize)
+	o.batch = make([]LexItem, 0, n)
+}
+
+func (o *output) send(item LexItem) {
+	if o.sink != nil {
+		if o.err == nil {
+			o.err = o.sink(item)
+		}
+		return
+	}
+
+	if o.batches == nil {
+		o.items <- item
+		return
+	}
+
+	o.batch = append(o.batch, item)
+	switch {
+	case len(o.batch) >= o.batchSize, item.Type() == ItemEOF, item.Type() == ItemError:
+		o.flush()
+	}
+}
+
+// flush sends the current batch, if any, to the consumer
+func (o *output) flush() {
+	if len(o.batch) == 0 {
+		return
+	}
+	o.batches <- o.batch
+	o.batch = make([]LexItem, 0, o.batchSize)
+}
+
+// close flushes any remaining items, and closes the channels
+func (o *output) close() {
+	if o.batches != nil {
+		o.flush()
+		close(o.batches)
+	}
+	close(o.items)
+}
+
+// next returns the next item, transparently draining batches if batching
+// is enabled. Returns nil once the lexer is done
+func (o *output) next() LexItem {
+	if o.batches == nil {
+		return <-o.items
+	}
+
+	if len(o.pending) == 0 {
+		batch, ok := <-o.batches
+		if !ok {
+			return nil
+		}
+		o.pending = batch
+	}
+
+	item := o.pending[0]
+	o.pending = o.pending[1:]
+	return item
+}
+
+// aborted returns true if the sink has requested lexing to stop
+func (o *output) aborted() bool {
+	return o.err != nil
+}
+
+// outputter is implemented by the lexers in this package, so that LexRun
+// can properly flush and close their output
+type outputter interface {
+	output() *output
+}
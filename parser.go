@@ -0,0 +1,188 @@
+package lex
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Diagnostic is an error found while parsing, along with the position
+// of the item that caused it
+type Diagnostic struct {
+	Pos  int
+	Line int
+	Item LexItem
+	Msg  string
+}
+
+// Error returns the string representation of the Diagnostic
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("line %d, pos %d: %s", d.Line, d.Pos, d.Msg)
+}
+
+// Diagnostics is a list of Diagnostic, in the order they were found
+type Diagnostics []Diagnostic
+
+// Error returns all of the diagnostics, one per line
+func (d Diagnostics) Error() string {
+	buf := bytes.Buffer{}
+	for i, diag := range d {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(diag.Error())
+	}
+	return buf.String()
+}
+
+// Parser wraps a Consumer with the helpers commonly needed to write
+// recursive descent parsers. Errors are recorded as Diagnostics, so that
+// the parser may Sync and carry on to report as many errors as possible.
+// Parser is itself a Consumer
+type Parser struct {
+	consumer Consumer
+	last     LexItem
+	diags    Diagnostics
+}
+
+// NewParser creates a new Parser reading items from `c`
+func NewParser(c Consumer) *Parser {
+	return &Parser{consumer: c}
+}
+
+// Peek returns the next item, but does not consume it
+func (p *Parser) Peek() LexItem {
+	return p.consumer.Peek()
+}
+
+// Consume returns the next item, and consumes it
+func (p *Parser) Consume() LexItem {
+	item := p.consumer.Consume()
+	if item != nil {
+		p.last = item
+	}
+	return item
+}
+
+// Backup moves 1 item back
+func (p *Parser) Backup() {
+	p.consumer.Backup()
+}
+
+// Backup2 pushes `t1` into the buffer, and moves 2 items back
+func (p *Parser) Backup2(t1 LexItem) {
+	p.consumer.Backup2(t1)
+}
+
+// Is returns true if the next item is of any of the given types. The
+// item is not consumed
+func (p *Parser) Is(types ...ItemType) bool {
+	item := p.Peek()
+	if item == nil {
+		return false
+	}
+	for _, t := range types {
+		if item.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Accept consumes the next item if it is of any of the given types
+func (p *Parser) Accept(types ...ItemType) bool {
+	if !p.Is(types...) {
+		return false
+	}
+	p.Consume()
+	return true
+}
+
+// Optional consumes and returns the next item if it is of type `t`.
+// Otherwise nil is returned, and nothing is consumed
+func (p *Parser) Optional(t ItemType) LexItem {
+	if !p.Is(t) {
+		return nil
+	}
+	return p.Consume()
+}
+
+// Expect consumes and returns the next item if it is of type `t`.
+// Otherwise a Diagnostic is recorded and returned, and the offending item
+// is left in place so that the caller can Sync
+func (p *Parser) Expect(t ItemType) (LexItem, error) {
+	if p.Is(t) {
+		return p.Consume(), nil
+	}
+
+	item := p.Peek()
+	switch {
+	case item == nil:
+		return nil, p.Errorf(nil, "expected %s, got end of input", t)
+	case item.Type() == ItemEOF:
+		return nil, p.Errorf(item, "expected %s, got EOF", t)
+	case item.Type() == ItemError:
+		return nil, p.Errorf(item, "%s", item.Value())
+	}
+	return nil, p.Errorf(item, "expected %s, got %s (%q)", t, item.Type(), item.Value())
+}
+
+// SeparatedList calls `elem` to parse an element, and then again for each
+// item of type `sep` that follows, e.g. to parse "a, b, c". The first
+// error returned by `elem` stops the list, and is returned
+func (p *Parser) SeparatedList(elem func(*Parser) error, sep ItemType) error {
+	if err := elem(p); err != nil {
+		return err
+	}
+	for p.Accept(sep) {
+		if err := elem(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync skips items until the next item is of any of the given types,
+// which is left in place. Use it to recover from an error, e.g. by
+// skipping to the end of the current statement. Sync stops at EOF, and
+// returns false if none of the given types were found
+func (p *Parser) Sync(types ...ItemType) bool {
+	for {
+		if p.Is(types...) {
+			return true
+		}
+		item := p.Peek()
+		if item == nil || item.Type() == ItemEOF {
+			return false
+		}
+		p.Consume()
+	}
+}
+
+// Errorf records a Diagnostic for `item`, and returns it. If `item` is
+// nil, the position of the last consumed item is used
+func (p *Parser) Errorf(item LexItem, format string, args ...interface{}) error {
+	d := Diagnostic{Item: item, Msg: fmt.Sprintf(format, args...)}
+	if item == nil {
+		item = p.last
+	}
+	if item != nil {
+		d.Pos = item.Pos()
+		d.Line = item.Line()
+	}
+	p.diags = append(p.diags, d)
+	return d
+}
+
+// Diagnostics returns the Diagnostics recorded so far
+func (p *Parser) Diagnostics() Diagnostics {
+	return p.diags
+}
+
+// Err returns the recorded Diagnostics as an error, or nil if there
+// were none
+func (p *Parser) Err() error {
+	if len(p.diags) == 0 {
+		return nil
+	}
+	return p.diags
+}
@@ -0,0 +1,95 @@
+package lex
+
+import (
+	"fmt"
+	"testing"
+)
+
+func parseSum(input string) ([]string, *Parser) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer(input, tlc.lexStart)
+	go l.Run()
+
+	var numbers []string
+	p := NewParser(NewItemConsume(Drop(l, ItemWhitespace)))
+	for !p.Is(ItemEOF) && p.Peek() != nil {
+		err := p.SeparatedList(func(p *Parser) error {
+			item, err := p.Expect(ItemNumber)
+			if err != nil {
+				return err
+			}
+			numbers = append(numbers, item.Value())
+			return nil
+		}, ItemOperator)
+		if err != nil {
+			// skip to the next number, and start over
+			if !p.Sync(ItemNumber) {
+				break
+			}
+		}
+	}
+	return numbers, p
+}
+
+func TestParser(t *testing.T) {
+	numbers, p := parseSum("1 + 23  +\n 4")
+	if err := p.Err(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if len(numbers) != 3 || numbers[0] != "1" || numbers[1] != "23" || numbers[2] != "4" {
+		t.Errorf("expected [1 23 4], got %v", numbers)
+	}
+	if !p.Accept(ItemEOF) {
+		t.Errorf("expected EOF")
+	}
+}
+
+func TestParser_Diagnostics(t *testing.T) {
+	numbers, p := parseSum("1 + + 2 +\n + 3 +")
+	if len(numbers) != 3 || numbers[0] != "1" || numbers[1] != "2" || numbers[2] != "3" {
+		t.Errorf("expected [1 2 3], got %v", numbers)
+	}
+
+	diags := p.Diagnostics()
+	expected := []struct {
+		line, pos int
+		typ       ItemType
+		msg       string
+	}{
+		{1, 4, ItemOperator, fmt.Sprintf("expected %s, got %s (%q)", ItemNumber, ItemOperator, "+")},
+		{2, 11, ItemOperator, fmt.Sprintf("expected %s, got %s (%q)", ItemNumber, ItemOperator, "+")},
+		{2, 16, ItemEOF, fmt.Sprintf("expected %s, got %s", ItemNumber, ItemEOF)},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %d: %s", len(expected), len(diags), diags)
+	}
+	for i, d := range diags {
+		e := expected[i]
+		if d.Line != e.line || d.Pos != e.pos || d.Item.Type() != e.typ || d.Msg != e.msg {
+			t.Errorf("diagnostic %d: expected line %d, pos %d, %s: %q, got %s: %q", i, e.line, e.pos, e.typ, e.msg, d.Item.Type(), d.Error())
+		}
+	}
+	if p.Err() == nil {
+		t.Errorf("expected Err() to be non-nil")
+	}
+}
+
+func TestParser_Optional(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer("1 +", tlc.lexStart)
+	go l.Run()
+
+	p := NewParser(NewItemConsume(l))
+	if item := p.Optional(ItemOperator); item != nil {
+		t.Errorf("expected nil, got %s", item)
+	}
+	if item := p.Optional(ItemNumber); item == nil || item.Value() != "1" {
+		t.Errorf("expected number, got %v", item)
+	}
+	if p.Accept(ItemOperator) {
+		t.Errorf("expected whitespace to be in the way")
+	}
+	if !p.Accept(ItemWhitespace, ItemOperator) || !p.Accept(ItemWhitespace, ItemOperator) {
+		t.Errorf("expected whitespace and operator to be accepted")
+	}
+}
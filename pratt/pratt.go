@@ -0,0 +1,226 @@
+/*
+Package pratt implements a Pratt (top down operator precedence) expression
+parser, which reads its items from a lex.Consumer.
+
+Handlers are registered per lex.ItemType, along with their binding power.
+Items that may start an expression (literals, prefix operators, opening
+parenthesis) have prefix handlers, and items that may follow an expression
+(binary, postfix and ternary operators) have infix handlers. The values
+produced by the handlers are up to you: they may be AST nodes, or the
+result of evaluating the expression right away.
+
+	p := pratt.New()
+	p.Literal(ItemNumber, func(item lex.LexItem) (interface{}, error) {
+	  return strconv.Atoi(item.Value())
+	})
+	p.Infix(ItemPlus, 10, pratt.Left, func(op lex.LexItem, left, right interface{}) (interface{}, error) {
+	  return left.(int) + right.(int), nil
+	})
+	v, err := p.Parse(lex.NewItemConsume(l))
+*/
+package pratt
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Assoc specifies the associativity of binary operators
+type Assoc int
+
+const (
+	// Left associative operators group from the left: a - b - c is (a - b) - c
+	Left Assoc = iota
+	// Right associative operators group from the right: a ^ b ^ c is a ^ (b ^ c)
+	Right
+)
+
+// PrefixFn handles an item that starts an expression. The item has
+// already been consumed
+type PrefixFn func(p *Parser, c lex.Consumer, item lex.LexItem) (interface{}, error)
+
+// InfixFn handles an item that follows the expression `left`. The item
+// has already been consumed
+type InfixFn func(p *Parser, c lex.Consumer, left interface{}, item lex.LexItem) (interface{}, error)
+
+// Error describes a parse error, along with the offending item. If the
+// input ended prematurely, Item is nil
+type Error struct {
+	Item lex.LexItem
+	Msg  string
+}
+
+// Error returns the string representation of the error
+func (e *Error) Error() string {
+	if e.Item == nil {
+		return e.Msg
+	}
+	return fmt.Sprintf("line %d, pos %d: %s", e.Item.Line(), e.Item.Pos(), e.Msg)
+}
+
+// Errorf creates a new *Error for the given item
+func Errorf(item lex.LexItem, format string, args ...interface{}) error {
+	return &Error{Item: item, Msg: fmt.Sprintf(format, args...)}
+}
+
+func unexpected(item lex.LexItem) error {
+	switch {
+	case item == nil:
+		return &Error{Msg: "unexpected end of input"}
+	case item.Type() == lex.ItemEOF:
+		return Errorf(item, "unexpected EOF")
+	case item.Type() == lex.ItemError:
+		return Errorf(item, "%s", item.Value())
+	}
+	return Errorf(item, "unexpected %s (%q)", item.Type(), item.Value())
+}
+
+type infixRule struct {
+	bp int
+	fn InfixFn
+}
+
+// Parser is a Pratt parser. Register the handlers for each ItemType,
+// and call Parse. A Parser may be reused once it has been set up
+type Parser struct {
+	prefix map[lex.ItemType]PrefixFn
+	infix  map[lex.ItemType]infixRule
+}
+
+// New creates a new Parser with no handlers
+func New() *Parser {
+	return &Parser{
+		prefix: make(map[lex.ItemType]PrefixFn),
+		infix:  make(map[lex.ItemType]infixRule),
+	}
+}
+
+// Nud registers a raw prefix handler for items of type `t`
+func (p *Parser) Nud(t lex.ItemType, fn PrefixFn) {
+	p.prefix[t] = fn
+}
+
+// Led registers a raw infix handler for items of type `t`, with the left
+// binding power `bp`. Higher binding powers bind tighter
+func (p *Parser) Led(t lex.ItemType, bp int, fn InfixFn) {
+	p.infix[t] = infixRule{bp: bp, fn: fn}
+}
+
+// Literal registers a handler for items of type `t` that form an
+// expression on their own, such as numbers or identifiers
+func (p *Parser) Literal(t lex.ItemType, fn func(item lex.LexItem) (interface{}, error)) {
+	p.Nud(t, func(_ *Parser, _ lex.Consumer, item lex.LexItem) (interface{}, error) {
+		return fn(item)
+	})
+}
+
+// Prefix registers a prefix operator, such as unary minus. The operand is
+// parsed with the binding power `bp`
+func (p *Parser) Prefix(t lex.ItemType, bp int, fn func(op lex.LexItem, operand interface{}) (interface{}, error)) {
+	p.Nud(t, func(p *Parser, c lex.Consumer, item lex.LexItem) (interface{}, error) {
+		operand, err := p.ParseExpr(c, bp)
+		if err != nil {
+			return nil, err
+		}
+		return fn(item, operand)
+	})
+}
+
+// Infix registers a binary operator with the binding power `bp` and
+// associativity `assoc`
+func (p *Parser) Infix(t lex.ItemType, bp int, assoc Assoc, fn func(op lex.LexItem, left, right interface{}) (interface{}, error)) {
+	rbp := bp
+	if assoc == Right {
+		rbp = bp - 1
+	}
+	p.Led(t, bp, func(p *Parser, c lex.Consumer, left interface{}, item lex.LexItem) (interface{}, error) {
+		right, err := p.ParseExpr(c, rbp)
+		if err != nil {
+			return nil, err
+		}
+		return fn(item, left, right)
+	})
+}
+
+// Postfix registers a postfix operator, such as factorial, with the
+// binding power `bp`
+func (p *Parser) Postfix(t lex.ItemType, bp int, fn func(op lex.LexItem, operand interface{}) (interface{}, error)) {
+	p.Led(t, bp, func(_ *Parser, _ lex.Consumer, left interface{}, item lex.LexItem) (interface{}, error) {
+		return fn(item, left)
+	})
+}
+
+// Ternary registers a right associative ternary operator such as
+// "cond ? a : b", where `t` is the first operator and `sep` the second
+func (p *Parser) Ternary(t, sep lex.ItemType, bp int, fn func(op lex.LexItem, cond, then, otherwise interface{}) (interface{}, error)) {
+	p.Led(t, bp, func(p *Parser, c lex.Consumer, cond interface{}, item lex.LexItem) (interface{}, error) {
+		then, err := p.ParseExpr(c, 0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := Expect(c, sep); err != nil {
+			return nil, err
+		}
+		otherwise, err := p.ParseExpr(c, bp-1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(item, cond, then, otherwise)
+	})
+}
+
+// Parse parses a whole expression
+func (p *Parser) Parse(c lex.Consumer) (interface{}, error) {
+	return p.ParseExpr(c, 0)
+}
+
+// ParseExpr parses an expression, for as long as the following infix
+// operators have a binding power greater than `rbp`. This is meant to be
+// called from within handlers
+func (p *Parser) ParseExpr(c lex.Consumer, rbp int) (interface{}, error) {
+	item := c.Consume()
+	if item == nil {
+		return nil, unexpected(nil)
+	}
+
+	nud, ok := p.prefix[item.Type()]
+	if !ok {
+		return nil, unexpected(item)
+	}
+
+	left, err := nud(p, c, item)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		next := c.Peek()
+		if next == nil {
+			break
+		}
+		rule, ok := p.infix[next.Type()]
+		if !ok || rule.bp <= rbp {
+			break
+		}
+
+		c.Consume()
+		if left, err = rule.fn(p, c, left, next); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// Expect consumes the next item, and returns an error if its type is
+// not `t`
+func Expect(c lex.Consumer, t lex.ItemType) (lex.LexItem, error) {
+	item := c.Consume()
+	if item == nil || item.Type() != t {
+		if item != nil && item.Type() != lex.ItemEOF && item.Type() != lex.ItemError {
+			return nil, Errorf(item, "expected %s, got %s (%q)", t, item.Type(), item.Value())
+		}
+		return nil, unexpected(item)
+	}
+	return item, nil
+}
@@ -0,0 +1,156 @@
+package pratt_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/lex"
+	"github.com/lestrrat-go/lex/pratt"
+)
+
+const (
+	ItemNumber lex.ItemType = lex.ItemDefaultMax + iota
+	ItemOperator
+	ItemLParen
+	ItemRParen
+)
+
+func lexExpr(l lex.Lexer) lex.LexFn {
+	l.AcceptRun(" ")
+	l.Ignore()
+
+	switch r := l.Next(); {
+	case r == lex.EOF:
+		l.Emit(lex.ItemEOF)
+		return nil
+	case r >= '0' && r <= '9':
+		l.AcceptRun("0123456789")
+		l.Emit(ItemNumber)
+	case r == '(':
+		l.Emit(ItemLParen)
+	case r == ')':
+		l.Emit(ItemRParen)
+	case strings.ContainsRune("+-*^!?:", r):
+		l.Emit(ItemOperator)
+	default:
+		return l.EmitErrorf("unexpected character %q", r)
+	}
+	return lexExpr
+}
+
+func TestParser(t *testing.T) {
+	// Operators are distinguished by ItemType in real grammars, so give
+	// each its own type by rewriting the items before they reach the parser
+	types := map[string]lex.ItemType{
+		"+": lex.ItemDefaultMax + 10,
+		"-": lex.ItemDefaultMax + 11,
+		"*": lex.ItemDefaultMax + 12,
+		"^": lex.ItemDefaultMax + 13,
+		"!": lex.ItemDefaultMax + 14,
+		"?": lex.ItemDefaultMax + 15,
+		":": lex.ItemDefaultMax + 16,
+	}
+
+	p := pratt.New()
+	p.Literal(ItemNumber, func(item lex.LexItem) (interface{}, error) {
+		return item.Value(), nil
+	})
+	p.Nud(ItemLParen, func(p *pratt.Parser, c lex.Consumer, item lex.LexItem) (interface{}, error) {
+		v, err := p.Parse(c)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pratt.Expect(c, ItemRParen); err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+	p.Prefix(types["-"], 30, func(_ lex.LexItem, v interface{}) (interface{}, error) {
+		return fmt.Sprintf("(-%s)", v), nil
+	})
+	binary := func(op string) func(lex.LexItem, interface{}, interface{}) (interface{}, error) {
+		return func(_ lex.LexItem, left, right interface{}) (interface{}, error) {
+			return fmt.Sprintf("(%s %s %s)", left, op, right), nil
+		}
+	}
+	p.Infix(types["+"], 10, pratt.Left, binary("+"))
+	p.Infix(types["-"], 10, pratt.Left, binary("-"))
+	p.Infix(types["*"], 20, pratt.Left, binary("*"))
+	p.Infix(types["^"], 40, pratt.Right, binary("^"))
+	p.Postfix(types["!"], 50, func(_ lex.LexItem, v interface{}) (interface{}, error) {
+		return fmt.Sprintf("(%s!)", v), nil
+	})
+	p.Ternary(types["?"], types[":"], 5, func(_ lex.LexItem, cond, then, otherwise interface{}) (interface{}, error) {
+		return fmt.Sprintf("(%s ? %s : %s)", cond, then, otherwise), nil
+	})
+
+	parse := func(input string) (interface{}, error) {
+		l := lex.NewStringLexer(input, lexExpr)
+		go l.Run()
+		src := lex.Map(l, func(item lex.LexItem) lex.LexItem {
+			if item.Type() != ItemOperator {
+				return item
+			}
+			return lex.NewItem(types[item.Value()], item.Pos(), item.Line(), item.Value())
+		})
+		return p.Parse(lex.NewItemConsume(src))
+	}
+
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"1", "1"},
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"1 * 2 + 3", "((1 * 2) + 3)"},
+		{"1 - 2 - 3", "((1 - 2) - 3)"},
+		{"2 ^ 3 ^ 4", "(2 ^ (3 ^ 4))"},
+		{"(1 + 2) * 3", "((1 + 2) * 3)"},
+		{"-1 * 2", "((-1) * 2)"},
+		{"-2 ^ 2", "(-(2 ^ 2))"},
+		{"3! + 1", "((3!) + 1)"},
+		{"-3!", "(-(3!))"},
+		{"1 ? 2 : 3", "(1 ? 2 : 3)"},
+		{"1 ? 2 : 3 ? 4 : 5", "(1 ? 2 : (3 ? 4 : 5))"},
+		{"1 + 1 ? 2 * 2 : 3", "((1 + 1) ? (2 * 2) : 3)"},
+		{"1 ? 2 ? 3 : 4 : 5", "(1 ? (2 ? 3 : 4) : 5)"},
+	}
+
+	for _, test := range tests {
+		v, err := parse(test.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.input, err)
+			continue
+		}
+		if v != test.expect {
+			t.Errorf("%q: expected %s, got %s", test.input, test.expect, v)
+		}
+	}
+
+	errors := []struct {
+		input  string
+		expect string
+	}{
+		{"1 +", "line 1, pos 3: unexpected EOF"},
+		{"1 + * 2", "line 1, pos 4: unexpected"},
+		{"(1 + 2", "line 1, pos 6: unexpected EOF"},
+		{"(1 2)", "line 1, pos 3: expected"},
+		{"1 ? 2", "line 1, pos 5: unexpected EOF"},
+		{"1 + $", "line 1, pos 5: unexpected character '$'"},
+	}
+
+	for _, test := range errors {
+		_, err := parse(test.input)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.input)
+			continue
+		}
+		if _, ok := err.(*pratt.Error); !ok {
+			t.Errorf("%q: expected *pratt.Error, got %T", test.input, err)
+		}
+		if !strings.HasPrefix(err.Error(), test.expect) {
+			t.Errorf("%q: expected error starting with %q, got %q", test.input, test.expect, err)
+		}
+	}
+}
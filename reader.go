@@ -15,32 +15,50 @@ type ReaderLexer struct {
 	pos        int
 	peekLoc    int
 	line       int
+	col        int
 	buf        []rune
-	items      chan LexItem
+	out        output
 	entryPoint LexFn
+	filename   string
+	open       func() (io.ReadCloser, error)
+	context    interface{}
 }
 
-// NewReaderLexer creats a ReaderLexer
-func NewReaderLexer(in io.Reader, fn LexFn) *ReaderLexer {
+// NewReaderLexer creats a ReaderLexer.
+// This is equivalent to New(ReaderSource(in), fn, options...)
+func NewReaderLexer(in io.Reader, fn LexFn, options ...Option) *ReaderLexer {
+	return New(ReaderSource(in), fn, options...).(*ReaderLexer)
+}
+
+func newReaderLexer(in io.Reader, fn LexFn, c *config) *ReaderLexer {
 	return &ReaderLexer{
 		bufio.NewReader(in),
 		0,
 		-1,
 		-1,
 		1,
+		1,
 		[]rune{},
-		make(chan LexItem, 1),
+		newOutput(c),
 		fn,
+		c.filename,
+		nil,
+		c.context,
 	}
 }
 
-// Current returns current rune being considered
-func (l *ReaderLexer) Current() (r rune) {
-	if len(l.buf) == 0 {
-		return l.Next()
-	}
+// Filename returns the name of the input, as specified by WithFilename
+// or FileSource
+func (l *ReaderLexer) Filename() string {
+	return l.filename
+}
 
-	return l.buf[l.peekLoc]
+// Current returns the rune at the cursor, which is the rune that the
+// next call to Next returns. The cursor is not moved
+func (l *ReaderLexer) Current() (r rune) {
+	r = l.Next()
+	l.Backup()
+	return r
 }
 
 // Next returns the next rune
@@ -124,7 +142,7 @@ func (l *ReaderLexer) Next() (r rune) {
 			l.peekLoc++
 			l.pos++
 			r = -1
-			if l.buf[len(l.buf)-1] != r {
+			if len(l.buf) == 0 || l.buf[len(l.buf)-1] != r {
 				l.buf = append(l.buf, r)
 			}
 		}
@@ -157,16 +175,63 @@ func (l *ReaderLexer) Peek() (r rune) {
 	return r
 }
 
-// Backup moves the cursor 1 position
+// Backup moves the cursor 1 position. It may be called repeatedly to
+// undo multiple calls to Next(), but never moves the cursor before the
+// end of the last item
 func (l *ReaderLexer) Backup() {
 	guard := Mark("Backup")
 	defer guard()
 
+	if l.pos < 0 {
+		return
+	}
+
 	l.pos--
 	l.peekLoc = l.pos // align
 	Trace("Backed up l.pos = %d", l.pos)
 }
 
+// Line returns the line number of the current cursor position
+func (l *ReaderLexer) Line() int {
+	line := l.line
+	for i := 0; len(l.buf) > i && i < l.pos+1; i++ {
+		if l.buf[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
+
+// Cursor returns the current cursor position, as a byte offset from the
+// beginning of the input
+func (l *ReaderLexer) Cursor() int {
+	pos := l.start
+	for i := 0; len(l.buf) > i && i < l.pos+1; i++ {
+		if l.buf[i] == EOF {
+			break
+		}
+		pos += utf8.RuneLen(l.buf[i])
+	}
+	return pos
+}
+
+// Column returns the column of the current cursor position, counted in
+// runes starting at 1
+func (l *ReaderLexer) Column() int {
+	col := l.col
+	for i := 0; len(l.buf) > i && i < l.pos+1; i++ {
+		switch l.buf[i] {
+		case EOF:
+			return col
+		case '\n':
+			col = 1
+		default:
+			col++
+		}
+	}
+	return col
+}
+
 // AcceptString returns true if the given string can be matched exactly.
 // This is a utility function to be called from concrete Lexer types
 func (l *ReaderLexer) AcceptString(word string) bool {
@@ -214,16 +279,58 @@ func (l *ReaderLexer) AcceptRunExcept(valid string) bool {
 	return AcceptRunExcept(l, valid)
 }
 
+// AcceptAnySet moves the cursor 1 rune if the rune is contained in the
+// given set
+func (l *ReaderLexer) AcceptAnySet(set *RuneSet) bool {
+	return AcceptAnySet(l, set)
+}
+
+// AcceptRunSet moves the cursor forward as long as the input matches one
+// of the runes in the given set
+func (l *ReaderLexer) AcceptRunSet(set *RuneSet) bool {
+	return AcceptRunSet(l, set)
+}
+
 // Emit creates and sends a new Item of type `t` through the output
 // channel. The Item is generated using `Grab`
 func (l *ReaderLexer) Emit(t ItemType) {
 	Trace("Emit %s", t)
-	l.items <- l.Grab(t)
+	l.out.send(l.Grab(t))
+}
+
+// EmitPayload works like Emit, but also associates a decoded value
+// (e.g. an int64 for a numeric literal, or the unescaped contents of a
+// string literal) with the Item, which is available via Payload()
+func (l *ReaderLexer) EmitPayload(t ItemType, v interface{}) {
+	Trace("EmitPayload %s", t)
+	item := l.Grab(t)
+	item.payload = v
+	l.out.send(item)
+}
+
+// EmitValue emits a synthetic Item of type `t` with the given value,
+// positioned at the current cursor. Unlike Emit, the buffer is left
+// untouched, so this can be used to emit virtual tokens (e.g. automatic
+// semicolons) in the middle of lexing something else
+func (l *ReaderLexer) EmitValue(t ItemType, v string) {
+	Trace("EmitValue %s", t)
+	item := NewItem(t, l.Cursor(), l.Line(), v)
+	item.col = l.Column()
+	l.out.send(item)
+}
+
+// EmitItem sends the given Item through the output channel as is.
+// The buffer is left untouched
+func (l *ReaderLexer) EmitItem(item LexItem) {
+	Trace("EmitItem %s", item.Type())
+	l.out.send(item)
 }
 
 // EmitErrorf emits an Error Item
 func (l *ReaderLexer) EmitErrorf(format string, args ...interface{}) LexFn {
-	l.items <- NewItem(ItemError, l.pos, l.line, fmt.Sprintf(format, args...))
+	item := NewItem(ItemError, l.Cursor(), l.Line(), fmt.Sprintf(format, args...))
+	item.col = l.Column()
+	l.out.send(item)
 	return nil
 }
 
@@ -276,12 +383,16 @@ func (l *ReaderLexer) Grab(t ItemType) Item {
 	line := l.line
 
 	strbuf := l.BufferString()
-	if strings.ContainsRune(strbuf, '\n') {
-		l.line++
-	}
+	l.line += strings.Count(strbuf, "\n")
 	strlen := len(strbuf)
 
 	item := NewItem(t, l.start, line, strbuf)
+	item.col = l.col
+	if i := strings.LastIndexByte(strbuf, '\n'); i >= 0 {
+		l.col = utf8.RuneCountInString(strbuf[i+1:]) + 1
+	} else {
+		l.col += utf8.RuneCountInString(strbuf)
+	}
 	l.buf = l.buf[utf8.RuneCountInString(strbuf):]
 	l.peekLoc = l.peekLoc - l.pos - 1
 	l.pos = -1
@@ -290,20 +401,66 @@ func (l *ReaderLexer) Grab(t ItemType) Item {
 	return item
 }
 
+// SetContext associates an arbitrary value with the lexer, so that
+// LexFn functions can keep their state (e.g. nesting depth, configuration)
+// without having to be bound to an object
+func (l *ReaderLexer) SetContext(v interface{}) {
+	l.context = v
+}
+
+// Context returns the value associated with SetContext or WithContext
+func (l *ReaderLexer) Context() interface{} {
+	return l.context
+}
+
+// Ignore discards the contents of the buffer, without emitting an Item
+func (l *ReaderLexer) Ignore() {
+	// Grab takes care of advancing the buffer. The Item itself is dropped
+	l.Grab(ItemEOF)
+}
+
 // GetEntryPoint returns the function that lexing is started with
 func (l *ReaderLexer) GetEntryPoint() LexFn {
 	return l.entryPoint
 }
 
-// Items returns the channel where lex'ed Item structs are sent to
+// SetBatchSize turns on batched delivery: items are sent through the
+// channel returned by Batches() in chunks of up to `n` items, instead of
+// one by one through Items(). Pending items are flushed when an EOF or
+// an Error item is emitted, and when lexing ends. A value less than 2
+// turns batching off. This must be called before Run()
+func (l *ReaderLexer) SetBatchSize(n int) {
+	l.out.setBatchSize(n)
+}
+
+// Items returns the channel where lex'ed Item structs are sent to.
+// If batching is enabled, no items are sent through this channel.
+// Use NextItem() or Batches() instead
 func (l *ReaderLexer) Items() chan LexItem {
-	return l.items
+	return l.out.items
+}
+
+// Err returns the error returned by the item sink specified via
+// WithItemSink, if any
+func (l *ReaderLexer) Err() error {
+	return l.out.err
+}
+
+// Batches returns the channel where batches of lex'ed Item structs are
+// sent to. It is nil unless batching has been enabled via SetBatchSize()
+func (l *ReaderLexer) Batches() chan []LexItem {
+	return l.out.batches
 }
 
 // NextItem returns the next Item in the processing pipeline.
-// This is just a convenience function over reading l.Items()
+// This is just a convenience function over reading l.Items(), or
+// l.Batches() if batching is enabled
 func (l *ReaderLexer) NextItem() LexItem {
-	return <-l.items
+	return l.out.next()
+}
+
+func (l *ReaderLexer) output() *output {
+	return &l.out
 }
 
 // Run starts the lexing. You should be calling this method as a goroutine:
@@ -315,5 +472,15 @@ func (l *ReaderLexer) NextItem() LexItem {
 //    }
 //
 func (l *ReaderLexer) Run() {
+	if l.open != nil {
+		in, err := l.open()
+		if err != nil {
+			l.EmitErrorf("%s", err)
+			l.out.close()
+			return
+		}
+		defer in.Close()
+		l.source = bufio.NewReader(in)
+	}
 	LexRun(l)
 }
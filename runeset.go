@@ -0,0 +1,116 @@
+package lex
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// RuneSet is a precompiled set of runes, to be used with AcceptRunSet and
+// AcceptAnySet. Membership of ASCII runes is resolved using a 128-bit
+// bitmap, and non-ASCII runes are looked up using binary search (or a
+// function, for sets created by NewRuneSetFunc), which is much faster
+// than calling strings.IndexRune for each rune. RuneSets are immutable,
+// and can be combined using Union and Negate
+type RuneSet struct {
+	ascii    [2]uint64
+	others   []rune
+	nonASCII func(rune) bool
+}
+
+// NewRuneSet creates a RuneSet containing the runes in `runes`
+func NewRuneSet(runes string) *RuneSet {
+	s := &RuneSet{}
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			s.ascii[r>>6] |= 1 << uint(r&63)
+			continue
+		}
+		s.others = append(s.others, r)
+	}
+	sort.Slice(s.others, func(i, j int) bool { return s.others[i] < s.others[j] })
+	return s
+}
+
+// NewRuneSetFunc creates a RuneSet containing the runes for which `fn`
+// returns true. `fn` is called once for each ASCII rune upon creation,
+// and for each non-ASCII rune passed to Contains
+func NewRuneSetFunc(fn func(rune) bool) *RuneSet {
+	s := &RuneSet{nonASCII: fn}
+	for r := rune(0); r < utf8.RuneSelf; r++ {
+		if fn(r) {
+			s.ascii[r>>6] |= 1 << uint(r&63)
+		}
+	}
+	return s
+}
+
+// Union creates a new RuneSet containing the runes that are in `s` or in
+// any of `others`
+func (s *RuneSet) Union(others ...*RuneSet) *RuneSet {
+	sets := append([]*RuneSet{s}, others...)
+	u := &RuneSet{ascii: s.ascii}
+	for _, o := range others {
+		u.ascii[0] |= o.ascii[0]
+		u.ascii[1] |= o.ascii[1]
+	}
+	u.nonASCII = func(r rune) bool {
+		for _, set := range sets {
+			if set.containsNonASCII(r) {
+				return true
+			}
+		}
+		return false
+	}
+	return u
+}
+
+// Negate creates a new RuneSet containing the runes that are not in `s`.
+// EOF is never part of a RuneSet, negated or not
+func (s *RuneSet) Negate() *RuneSet {
+	return &RuneSet{
+		ascii:    [2]uint64{^s.ascii[0], ^s.ascii[1]},
+		nonASCII: func(r rune) bool { return !s.containsNonASCII(r) },
+	}
+}
+
+// Contains returns true if `r` is in the set. EOF is never in the set
+func (s *RuneSet) Contains(r rune) bool {
+	if r < 0 {
+		return false
+	}
+	if r < utf8.RuneSelf {
+		return s.ascii[r>>6]&(1<<uint(r&63)) != 0
+	}
+	return s.containsNonASCII(r)
+}
+
+func (s *RuneSet) containsNonASCII(r rune) bool {
+	if s.nonASCII != nil {
+		return s.nonASCII(r)
+	}
+	i := sort.Search(len(s.others), func(i int) bool { return s.others[i] >= r })
+	return i < len(s.others) && s.others[i] == r
+}
+
+// AcceptAnySet moves the cursor 1 rune if the rune is contained in the
+// given set. This is a utility function to be called from concrete Lexer
+// types
+func AcceptAnySet(l Lexer, set *RuneSet) bool {
+	if set.Contains(l.Next()) {
+		return true
+	}
+	l.Backup()
+	return false
+}
+
+// AcceptRunSet moves the cursor forward as long as the input matches one
+// of the runes in the given set. This is a utility function to be called
+// from concrete Lexer types
+func AcceptRunSet(l Lexer, set *RuneSet) bool {
+	count := 0
+	for set.Contains(l.Next()) {
+		count++
+	}
+	l.Backup()
+	return count > 0
+}
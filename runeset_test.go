@@ -0,0 +1,87 @@
+package lex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRuneSet(t *testing.T) {
+	set := NewRuneSet("0123456789äö\n")
+	for _, r := range "0123456789äö\n" {
+		if !set.Contains(r) {
+			t.Errorf("expected %q to be in the set", r)
+		}
+	}
+	for _, r := range "abü " {
+		if set.Contains(r) {
+			t.Errorf("expected %q not to be in the set", r)
+		}
+	}
+	if set.Contains(EOF) {
+		t.Errorf("expected EOF not to be in the set")
+	}
+
+	greek := NewRuneSetFunc(func(r rune) bool { return r >= 'α' && r <= 'ω' })
+	for r, expected := range map[rune]bool{'β': true, '5': true, 'ü': false, 'a': false} {
+		if got := greek.Union(set).Contains(r); got != expected {
+			t.Errorf("expected %q in the union to be %t, got %t", r, expected, got)
+		}
+		if got := greek.Union(set).Negate().Contains(r); got == expected {
+			t.Errorf("expected %q in the negated union to be %t, got %t", r, !expected, got)
+		}
+	}
+
+	for _, l := range []Lexer{
+		NewStringLexer("12ä\n34 x", nil),
+		NewReaderLexer(bytes.NewBufferString("12ä\n34 x"), nil),
+	} {
+		if l.AcceptAnySet(NewRuneSet("x")) {
+			t.Errorf("expected AcceptAnySet to fail")
+		}
+		if !l.AcceptAnySet(set) {
+			t.Errorf("expected AcceptAnySet to succeed")
+		}
+		if !l.AcceptRunSet(set) {
+			t.Errorf("expected AcceptRunSet to succeed")
+		}
+		if buf := l.BufferString(); buf != "12ä\n34" {
+			t.Errorf("expected buffer to be %q, got %q", "12ä\n34", buf)
+		}
+		if l.Line() != 2 {
+			t.Errorf("expected line to be 2, got %d", l.Line())
+		}
+		l.Backup()
+		if buf := l.BufferString(); buf != "12ä\n3" {
+			t.Errorf("expected buffer to be %q, got %q", "12ä\n3", buf)
+		}
+		if l.AcceptRunSet(NewRuneSet("x")) {
+			t.Errorf("expected AcceptRunSet to fail")
+		}
+	}
+}
+
+var benchDigits = strings.Repeat("0123456789", 10000)
+
+func BenchmarkAcceptRun(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		l := NewStringLexer(benchDigits, nil)
+		l.AcceptRun("0123456789")
+	}
+}
+
+func BenchmarkAcceptRunSet(b *testing.B) {
+	set := NewRuneSet("0123456789")
+	for i := 0; i < b.N; i++ {
+		l := NewStringLexer(benchDigits, nil)
+		l.AcceptRunSet(set)
+	}
+}
+
+func BenchmarkAcceptRunSet_Reader(b *testing.B) {
+	set := NewRuneSet("0123456789")
+	for i := 0; i < b.N; i++ {
+		l := NewReaderLexer(strings.NewReader(benchDigits), nil)
+		l.AcceptRunSet(set)
+	}
+}
@@ -0,0 +1,50 @@
+package scan
+
+import (
+	"unicode"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// CharClass is a compiled set of runes. It is the same type as
+// lex.RuneSet, so the ASCII bitmap and the lookup of non-ASCII runes are
+// shared with the lexers, and a CharClass can be passed to AcceptRunSet
+// and AcceptAnySet directly. CharClasses are immutable, and can be
+// combined using Union and Negate
+type CharClass = lex.RuneSet
+
+// NewCharClass creates a CharClass containing the runes in `runes`
+func NewCharClass(runes string) *CharClass {
+	return lex.NewRuneSet(runes)
+}
+
+// RangeClass creates a CharClass containing the runes from `lo` to `hi`,
+// inclusive
+func RangeClass(lo, hi rune) *CharClass {
+	return lex.NewRuneSetFunc(func(r rune) bool { return r >= lo && r <= hi })
+}
+
+// TableClass creates a CharClass containing the runes in any of the
+// given tables, such as unicode.Letter
+func TableClass(tables ...*unicode.RangeTable) *CharClass {
+	return lex.NewRuneSetFunc(func(r rune) bool { return unicode.IsOneOf(tables, r) })
+}
+
+// AcceptClass moves the cursor 1 rune if the rune is in the CharClass
+func AcceptClass(l lex.Lexer, c *CharClass) bool {
+	return l.AcceptAnySet(c)
+}
+
+// AcceptRunClass moves the cursor forward as long as the input matches
+// the CharClass
+func AcceptRunClass(l lex.Lexer, c *CharClass) bool {
+	return l.AcceptRunSet(c)
+}
+
+// AcceptRunTable moves the cursor forward as long as the input matches
+// any of the given unicode.RangeTables
+func AcceptRunTable(l lex.Lexer, tables ...*unicode.RangeTable) bool {
+	return l.AcceptRunFunc(func(r rune) bool {
+		return unicode.IsOneOf(tables, r)
+	})
+}
@@ -0,0 +1,41 @@
+package scan
+
+import "github.com/lestrrat-go/lex"
+
+// AcceptLineComment accepts a comment that starts with `prefix` (e.g. "//",
+// "#" or "--") and runs until the end of the line. The terminating newline
+// is not accepted. Returns false if there is no comment at the cursor
+func AcceptLineComment(l lex.Lexer, prefix string) bool {
+	if prefix == "" || !l.AcceptString(prefix) {
+		return false
+	}
+	l.AcceptRunExcept("\n")
+	return true
+}
+
+// AcceptBlockComment accepts a comment delimited by `open` and `close`
+// (e.g. "/*" and "*/"). If `nested` is true, comments may be nested
+// within each other, as in "(* (* *) *)". `ok` reports whether a comment
+// was found at the cursor: if it is false, the cursor is not moved.
+// An error pointing to the opening delimiter is returned if the comment
+// is unterminated
+func AcceptBlockComment(l lex.Lexer, open, close string, nested bool) (ok bool, err error) {
+	if open == "" || close == "" || !l.AcceptString(open) {
+		return false, nil
+	}
+
+	line := l.Line()
+	pos := l.Cursor() - len(open)
+
+	for depth := 1; depth > 0; {
+		switch {
+		case l.AcceptString(close):
+			depth--
+		case nested && l.AcceptString(open):
+			depth++
+		case l.Next() == lex.EOF:
+			return true, &Error{Line: line, Pos: pos, Msg: "unterminated comment"}
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package scan
+
+// Specs for numeric literals in some popular languages
+var (
+	// GoNumber describes Go integer and floating point literals.
+	// Imaginary literals are accepted via the "i" suffix
+	GoNumber = NumberSpec{
+		Hex:         true,
+		Octal:       true,
+		Binary:      true,
+		ZeroPrefix:  ZeroPrefixOctal,
+		Underscores: true,
+		Float:       true,
+		LeadingDot:  true,
+		TrailingDot: true,
+		Suffixes:    "i",
+	}
+
+	// CNumber describes C integer and floating point constants,
+	// including their type suffixes
+	CNumber = NumberSpec{
+		Hex:         true,
+		ZeroPrefix:  ZeroPrefixOctal,
+		Float:       true,
+		LeadingDot:  true,
+		TrailingDot: true,
+		Suffixes:    "uUlLfF",
+	}
+
+	// JSONNumber describes JSON numbers
+	JSONNumber = NumberSpec{
+		Sign:       true,
+		ZeroPrefix: ZeroPrefixStrict,
+		Float:      true,
+	}
+
+	// PythonNumber describes Python 3 integer and floating point literals.
+	// Imaginary literals are accepted via the "j" and "J" suffixes
+	PythonNumber = NumberSpec{
+		Hex:         true,
+		Octal:       true,
+		Binary:      true,
+		ZeroPrefix:  ZeroPrefixInvalid,
+		Underscores: true,
+		Float:       true,
+		LeadingDot:  true,
+		TrailingDot: true,
+		Suffixes:    "jJ",
+	}
+)
+
+// Specs for quoted literals in some popular languages
+var (
+	// GoString describes Go interpreted string literals
+	GoString = QuoteSpec{
+		Quotes:      `"`,
+		Escapes:     `abfnrtv\"`,
+		Hex:         true,
+		Octal:       true,
+		OctalFixed:  true,
+		Unicode:     true,
+		UnicodeLong: true,
+		ByteEscapes: true,
+	}
+
+	// GoRune describes Go rune literals
+	GoRune = QuoteSpec{
+		Quotes:      `'`,
+		Escapes:     `abfnrtv\'`,
+		Hex:         true,
+		Octal:       true,
+		OctalFixed:  true,
+		Unicode:     true,
+		UnicodeLong: true,
+		ByteEscapes: true,
+		Char:        true,
+	}
+
+	// CString describes C string literals
+	CString = QuoteSpec{
+		Quotes:           `"`,
+		Escapes:          `abfnrtv\'"?`,
+		HexVariable:      true,
+		Octal:            true,
+		Unicode:          true,
+		UnicodeLong:      true,
+		ByteEscapes:      true,
+		LineContinuation: true,
+	}
+
+	// CChar describes C character constants
+	CChar = QuoteSpec{
+		Quotes:      `'`,
+		Escapes:     `abfnrtv\'"?`,
+		HexVariable: true,
+		Octal:       true,
+		Unicode:     true,
+		UnicodeLong: true,
+		ByteEscapes: true,
+		Char:        true,
+	}
+
+	// JSONString describes JSON strings
+	JSONString = QuoteSpec{
+		Quotes:     `"`,
+		Escapes:    `bfnrt\/"`,
+		Unicode:    true,
+		Surrogates: true,
+	}
+
+	// PythonString describes Python 3 single-line string literals,
+	// quoted with either single or double quotes
+	PythonString = QuoteSpec{
+		Quotes:           `'"`,
+		Escapes:          `abfnrtv\'"`,
+		Hex:              true,
+		Octal:            true,
+		Unicode:          true,
+		UnicodeLong:      true,
+		LineContinuation: true,
+	}
+)
@@ -0,0 +1,97 @@
+package scan
+
+import (
+	"unicode"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Runes that are in ID_Start or ID_Continue, but are excluded from their
+// XID_ counterparts because they are not closed under NFKC normalization
+var (
+	notXIDStart = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{Lo: 0x037a, Hi: 0x037a, Stride: 1},
+			{Lo: 0x0e33, Hi: 0x0eb3, Stride: 0x80},
+			{Lo: 0x309b, Hi: 0x309c, Stride: 1},
+			{Lo: 0xfc5e, Hi: 0xfc63, Stride: 1},
+			{Lo: 0xfdfa, Hi: 0xfdfb, Stride: 1},
+			{Lo: 0xfe70, Hi: 0xfe7e, Stride: 2},
+			{Lo: 0xff9e, Hi: 0xff9f, Stride: 1},
+		},
+	}
+	notXIDContinue = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{Lo: 0x037a, Hi: 0x037a, Stride: 1},
+			{Lo: 0x309b, Hi: 0x309c, Stride: 1},
+			{Lo: 0xfc5e, Hi: 0xfc63, Stride: 1},
+			{Lo: 0xfdfa, Hi: 0xfdfb, Stride: 1},
+			{Lo: 0xfe70, Hi: 0xfe7e, Stride: 2},
+		},
+	}
+)
+
+func isIDStart(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+func isIDContinue(r rune) bool {
+	return (isIDStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// Character classes for identifiers, as defined by UAX #31. They are
+// derived from the tables in the unicode package, so they follow the
+// Unicode version supported by the Go runtime
+var (
+	// XIDStart contains the runes with the XID_Start property
+	XIDStart = lex.NewRuneSetFunc(func(r rune) bool {
+		return isIDStart(r) && !unicode.Is(notXIDStart, r)
+	})
+	// XIDContinue contains the runes with the XID_Continue property
+	XIDContinue = lex.NewRuneSetFunc(func(r rune) bool {
+		return isIDContinue(r) && !unicode.Is(notXIDContinue, r)
+	})
+)
+
+// IdentSpec describes the syntax of identifiers: a rune from Start,
+// followed by any number of runes from Continue
+type IdentSpec struct {
+	Start    *CharClass
+	Continue *CharClass
+}
+
+// Specs for identifiers
+var (
+	// UnicodeIdent describes the default identifiers defined by UAX #31,
+	// with the addition of '_' as a start character, as is customary in
+	// most programming languages
+	UnicodeIdent = IdentSpec{
+		Start:    XIDStart.Union(NewCharClass("_")),
+		Continue: XIDContinue,
+	}
+
+	// GoIdent describes Go identifiers
+	GoIdent = IdentSpec{
+		Start:    TableClass(unicode.Letter).Union(NewCharClass("_")),
+		Continue: TableClass(unicode.Letter, unicode.Digit).Union(NewCharClass("_")),
+	}
+
+	// ASCIIIdent describes identifiers made of ASCII letters, digits and
+	// '_', which may not start with a digit
+	ASCIIIdent = IdentSpec{
+		Start:    RangeClass('a', 'z').Union(RangeClass('A', 'Z'), NewCharClass("_")),
+		Continue: RangeClass('a', 'z').Union(RangeClass('A', 'Z'), RangeClass('0', '9'), NewCharClass("_")),
+	}
+)
+
+// AcceptIdentifier accepts an identifier described by `spec`. Returns
+// false if there is no identifier at the cursor
+func AcceptIdentifier(l lex.Lexer, spec IdentSpec) bool {
+	if !AcceptClass(l, spec.Start) {
+		return false
+	}
+	AcceptRunClass(l, spec.Continue)
+	return true
+}
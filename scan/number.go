@@ -0,0 +1,213 @@
+package scan
+
+import (
+	"strings"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// NumberKind describes the kind of number accepted by AcceptNumber
+type NumberKind int
+
+const (
+	// NumberNone means that there was no number at the cursor
+	NumberNone NumberKind = iota
+	// NumberInt means that an integer was accepted
+	NumberInt
+	// NumberFloat means that a floating point number was accepted
+	NumberFloat
+)
+
+// ZeroPrefix describes how decimal literals starting with a 0 are treated
+type ZeroPrefix int
+
+const (
+	// ZeroPrefixDecimal means that leading zeros are ignored
+	ZeroPrefixDecimal ZeroPrefix = iota
+	// ZeroPrefixOctal means that integers with a leading zero are octal
+	// numbers, as in C and Go
+	ZeroPrefixOctal
+	// ZeroPrefixInvalid means that integers with a leading zero are
+	// errors, unless they are all zeros, as in Python. Floats may have
+	// leading zeros
+	ZeroPrefixInvalid
+	// ZeroPrefixStrict means that leading zeros are errors, both in
+	// integers and floats, as in JSON
+	ZeroPrefixStrict
+)
+
+// NumberSpec describes the syntax of numeric literals
+type NumberSpec struct {
+	// Sign allows a leading '-' as part of the literal
+	Sign bool
+	// Hex allows hexadecimal integers with a 0x or 0X prefix
+	Hex bool
+	// Octal allows octal integers with a 0o or 0O prefix
+	Octal bool
+	// Binary allows binary integers with a 0b or 0B prefix
+	Binary bool
+	// ZeroPrefix specifies how decimal literals starting with 0 are treated
+	ZeroPrefix ZeroPrefix
+	// Underscores allows '_' between digits, and after a base prefix
+	Underscores bool
+	// Float allows fractions and exponents
+	Float bool
+	// LeadingDot allows floats without an integer part, such as ".5"
+	LeadingDot bool
+	// TrailingDot allows floats without a fractional part, such as "1."
+	TrailingDot bool
+	// Suffixes contains the runes that may follow the literal, such as
+	// "uUlL" in C or "i" (imaginary) in Go
+	Suffixes string
+}
+
+// AcceptNumber accepts a numeric literal described by `spec`. If there is
+// no number at the cursor, NumberNone is returned and the cursor is
+// not moved. An error is returned if the literal is malformed.
+//
+// Hexadecimal floats are not supported
+func AcceptNumber(l lex.Lexer, spec NumberSpec) (NumberKind, error) {
+	signed := false
+	if spec.Sign && l.Peek() == '-' {
+		l.Next()
+		signed = true
+	}
+
+	r := l.Next()
+	if r == '.' && spec.Float && spec.LeadingDot && isDigit(l.Peek(), 10) {
+		l.Backup()
+		return acceptDecimal(l, spec, "")
+	}
+
+	if !isDigit(r, 10) {
+		l.Backup()
+		if signed {
+			return NumberNone, errorf(l, "expected digit after '-'")
+		}
+		return NumberNone, nil
+	}
+
+	if r == '0' {
+		if base, name := basePrefix(l.Peek(), spec); base != 0 {
+			l.Next()
+			digits, err := acceptDigits(l, base, spec.Underscores, true)
+			if err != nil {
+				return NumberNone, err
+			}
+			if len(digits) == 0 {
+				return NumberNone, errorf(l, "%s literal has no digits", name)
+			}
+			if d := l.Peek(); isDigit(d, 10) {
+				return NumberNone, errorf(l, "invalid digit %q in %s literal", d, name)
+			}
+			acceptSuffixes(l, spec)
+			return NumberInt, nil
+		}
+	}
+
+	l.Backup()
+	intPart, err := acceptDigits(l, 10, spec.Underscores, false)
+	if err != nil {
+		return NumberNone, err
+	}
+	return acceptDecimal(l, spec, intPart)
+}
+
+// acceptDecimal accepts the rest of a decimal literal, whose integer
+// part has already been accepted
+func acceptDecimal(l lex.Lexer, spec NumberSpec, intPart string) (NumberKind, error) {
+	kind := NumberInt
+	if spec.Float && l.Peek() == '.' {
+		l.Next()
+		fraction, err := acceptDigits(l, 10, spec.Underscores, false)
+		if err != nil {
+			return NumberNone, err
+		}
+		if len(fraction) == 0 && !spec.TrailingDot {
+			return NumberNone, errorf(l, "expected digit after '.'")
+		}
+		kind = NumberFloat
+	}
+
+	if spec.Float && (l.Peek() == 'e' || l.Peek() == 'E') {
+		l.Next()
+		if r := l.Peek(); r == '+' || r == '-' {
+			l.Next()
+		}
+		exponent, err := acceptDigits(l, 10, spec.Underscores, false)
+		if err != nil {
+			return NumberNone, err
+		}
+		if len(exponent) == 0 {
+			return NumberNone, errorf(l, "exponent has no digits")
+		}
+		kind = NumberFloat
+	}
+
+	if len(intPart) > 1 && intPart[0] == '0' {
+		switch spec.ZeroPrefix {
+		case ZeroPrefixOctal:
+			if i := strings.IndexAny(intPart, "89"); kind == NumberInt && i >= 0 {
+				return NumberNone, errorf(l, "invalid digit %q in octal literal", intPart[i])
+			}
+		case ZeroPrefixInvalid:
+			if kind == NumberInt && strings.Trim(intPart, "0") != "" {
+				return NumberNone, errorf(l, "leading zeros in decimal integer literals are not permitted")
+			}
+		case ZeroPrefixStrict:
+			return NumberNone, errorf(l, "leading zeros are not permitted")
+		}
+	}
+
+	acceptSuffixes(l, spec)
+	return kind, nil
+}
+
+func basePrefix(r rune, spec NumberSpec) (int, string) {
+	switch {
+	case spec.Hex && (r == 'x' || r == 'X'):
+		return 16, "hexadecimal"
+	case spec.Octal && (r == 'o' || r == 'O'):
+		return 8, "octal"
+	case spec.Binary && (r == 'b' || r == 'B'):
+		return 2, "binary"
+	}
+	return 0, ""
+}
+
+// acceptDigits accepts a run of digits in the given base, and returns
+// them without the separating underscores
+func acceptDigits(l lex.Lexer, base int, underscores, leadingUnderscore bool) (string, error) {
+	var digits []rune
+	underscore := false
+	for {
+		r := l.Next()
+		if isDigit(r, base) {
+			digits = append(digits, r)
+			underscore = false
+			continue
+		}
+
+		if r == '_' && underscores {
+			if underscore || (len(digits) == 0 && !leadingUnderscore) {
+				return "", errorf(l, "'_' must separate successive digits")
+			}
+			underscore = true
+			continue
+		}
+
+		l.Backup()
+		break
+	}
+
+	if underscore {
+		return "", errorf(l, "'_' must separate successive digits")
+	}
+	return string(digits), nil
+}
+
+func acceptSuffixes(l lex.Lexer, spec NumberSpec) {
+	if spec.Suffixes != "" {
+		l.AcceptRun(spec.Suffixes)
+	}
+}
@@ -0,0 +1,248 @@
+package scan
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// QuoteSpec describes the syntax of quoted string and character literals
+type QuoteSpec struct {
+	// Quotes contains the runes that may open a literal. The literal must
+	// be closed by the same rune
+	Quotes string
+	// Escapes contains the runes that may follow a backslash to form a
+	// simple escape sequence, such as "n" for "\n" or `"` for `\"`
+	Escapes string
+	// Hex allows \xHH escapes, with exactly two hexadecimal digits
+	Hex bool
+	// HexVariable allows \x escapes with any number of hexadecimal digits,
+	// as in C
+	HexVariable bool
+	// Octal allows \ooo escapes, with up to three octal digits
+	Octal bool
+	// OctalFixed requires octal escapes to have exactly three digits,
+	// as in Go
+	OctalFixed bool
+	// Unicode allows \uHHHH escapes
+	Unicode bool
+	// UnicodeLong allows \UHHHHHHHH escapes
+	UnicodeLong bool
+	// Surrogates allows \uHHHH escapes to form UTF-16 surrogate pairs, as
+	// in JSON. Unpaired surrogates are decoded as U+FFFD
+	Surrogates bool
+	// ByteEscapes specifies that hexadecimal and octal escapes denote
+	// bytes rather than code points, as in Go and C
+	ByteEscapes bool
+	// Multiline allows unescaped newlines in the literal
+	Multiline bool
+	// LineContinuation specifies that a backslash followed by a newline
+	// is removed from the literal, as in C and Python
+	LineContinuation bool
+	// Char requires the literal to contain exactly one character
+	Char bool
+}
+
+// AcceptQuoted accepts a quoted literal described by `spec`, and returns
+// its unescaped contents. `ok` reports whether a literal was found at the
+// cursor: if it is false, the cursor is not moved. An error is returned if
+// the literal is malformed or unterminated.
+//
+// Named escapes such as Python's \N{...} are not supported
+func AcceptQuoted(l lex.Lexer, spec QuoteSpec) (value string, ok bool, err error) {
+	q := l.Next()
+	if q == lex.EOF || !strings.ContainsRune(spec.Quotes, q) {
+		l.Backup()
+		return "", false, nil
+	}
+
+	line := l.Line()
+	pos := l.Cursor() - utf8.RuneLen(q)
+	unterminated := func() error {
+		msg := "unterminated string literal"
+		if spec.Char {
+			msg = "unterminated character literal"
+		}
+		return &Error{Line: line, Pos: pos, Msg: msg}
+	}
+
+	var buf []byte
+	chars := 0
+	for {
+		r := l.Next()
+		switch r {
+		case q:
+			if spec.Char {
+				switch {
+				case chars == 0:
+					return "", true, errorf(l, "empty character literal")
+				case chars > 1:
+					return "", true, errorf(l, "more than one character in character literal")
+				}
+			}
+			return string(buf), true, nil
+		case lex.EOF:
+			return "", true, unterminated()
+		case '\n':
+			if !spec.Multiline {
+				// report the error at the end of the line the literal is on
+				l.Backup()
+				return "", true, errorf(l, "newline in literal")
+			}
+			buf = append(buf, '\n')
+			chars++
+		case '\\':
+			var n int
+			buf, n, err = acceptEscape(l, spec, buf)
+			if err != nil {
+				return "", true, err
+			}
+			chars += n
+		default:
+			buf = append(buf, string(r)...)
+			chars++
+		}
+	}
+}
+
+var simpleEscapes = map[rune]rune{
+	'a': '\a',
+	'b': '\b',
+	'f': '\f',
+	'n': '\n',
+	'r': '\r',
+	't': '\t',
+	'v': '\v',
+}
+
+// acceptEscape accepts the escape sequence following a backslash, and
+// appends its value to buf. Returns the number of characters appended
+func acceptEscape(l lex.Lexer, spec QuoteSpec, buf []byte) ([]byte, int, error) {
+	e := l.Next()
+	switch {
+	case e == lex.EOF:
+		return nil, 0, errorf(l, "unterminated escape sequence")
+	case e == '\n' && spec.LineContinuation:
+		return buf, 0, nil
+	case strings.ContainsRune(spec.Escapes, e):
+		if v, ok := simpleEscapes[e]; ok {
+			e = v
+		}
+		return append(buf, string(e)...), 1, nil
+	case e == 'x' && (spec.Hex || spec.HexVariable):
+		max := 2
+		if spec.HexVariable {
+			max = -1
+		}
+		v, n := acceptValue(l, 16, max)
+		switch {
+		case n == 0 || (!spec.HexVariable && n != 2):
+			return nil, 0, errorf(l, "invalid hexadecimal escape sequence")
+		case spec.ByteEscapes && v > 0xff:
+			return nil, 0, errorf(l, "hexadecimal escape value > 255: %d", v)
+		}
+		return appendValue(buf, spec, v)
+	case isDigit(e, 8) && spec.Octal:
+		l.Backup()
+		v, n := acceptValue(l, 8, 3)
+		switch {
+		case spec.OctalFixed && n != 3:
+			return nil, 0, errorf(l, "invalid octal escape sequence")
+		case v > 0xff:
+			return nil, 0, errorf(l, "octal escape value > 255: %d", v)
+		}
+		return appendValue(buf, spec, v)
+	case e == 'u' && spec.Unicode, e == 'U' && spec.UnicodeLong:
+		size := 4
+		if e == 'U' {
+			size = 8
+		}
+		v, n := acceptValue(l, 16, size)
+		if n != size {
+			return nil, 0, errorf(l, `invalid \%c escape sequence`, e)
+		}
+
+		r := rune(v)
+		if utf16.IsSurrogate(r) && spec.Surrogates {
+			if r < 0xdc00 && l.PeekString(`\u`) {
+				l.AcceptString(`\u`)
+				v2, n := acceptValue(l, 16, 4)
+				if n != 4 {
+					return nil, 0, errorf(l, `invalid \u escape sequence`)
+				}
+				if pair := utf16.DecodeRune(r, rune(v2)); pair != utf8.RuneError {
+					return append(buf, string(pair)...), 1, nil
+				}
+
+				// Not a pair, so each half stands on its own
+				buf = append(buf, string(utf8.RuneError)...)
+				if r = rune(v2); utf16.IsSurrogate(r) {
+					r = utf8.RuneError
+				}
+				return append(buf, string(r)...), 2, nil
+			}
+			return append(buf, string(utf8.RuneError)...), 1, nil
+		}
+
+		if !utf8.ValidRune(r) {
+			return nil, 0, errorf(l, "escape sequence is invalid Unicode code point")
+		}
+		return append(buf, string(r)...), 1, nil
+	}
+
+	return nil, 0, errorf(l, `invalid escape \%c`, e)
+}
+
+// acceptValue accepts up to `max` digits (unlimited if max < 0) in the
+// given base, and returns their value along with the number of digits
+func acceptValue(l lex.Lexer, base int, max int) (int, int) {
+	v := 0
+	n := 0
+	for ; max < 0 || n < max; n++ {
+		r := l.Next()
+		if !isDigit(r, base) {
+			l.Backup()
+			break
+		}
+		if v <= utf8.MaxRune {
+			v = v*base + digitValue(r)
+		}
+	}
+	return v, n
+}
+
+func appendValue(buf []byte, spec QuoteSpec, v int) ([]byte, int, error) {
+	if spec.ByteEscapes {
+		return append(buf, byte(v)), 1, nil
+	}
+	return append(buf, string(rune(v))...), 1, nil
+}
+
+// AcceptRawString accepts a raw string literal, which starts and ends with
+// `delim` (e.g. "`" in Go, or `"""`), and returns its contents verbatim.
+// `ok` reports whether a literal was found at the cursor: if it is false,
+// the cursor is not moved. An error is returned if the literal is
+// unterminated
+func AcceptRawString(l lex.Lexer, delim string) (value string, ok bool, err error) {
+	if delim == "" || !l.AcceptString(delim) {
+		return "", false, nil
+	}
+
+	line := l.Line()
+	pos := l.Cursor() - len(delim)
+
+	var buf []rune
+	for {
+		if l.AcceptString(delim) {
+			return string(buf), true, nil
+		}
+
+		r := l.Next()
+		if r == lex.EOF {
+			return "", true, &Error{Line: line, Pos: pos, Msg: "unterminated raw string literal"}
+		}
+		buf = append(buf, r)
+	}
+}
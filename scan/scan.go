@@ -0,0 +1,70 @@
+/*
+Package scan contains helpers to accept common literals, such as numbers,
+quoted strings and raw strings, from a lex.Lexer. The exact syntax of
+each literal is described by a spec, and specs for the dialects of some
+popular languages (Go, C, JSON, Python) are provided.
+
+The helpers are meant to be called from within a lex.LexFn. Upon success
+the literal is left in the lexer's buffer, ready to be emitted:
+
+	func lexNumber(l lex.Lexer) lex.LexFn {
+	  kind, err := scan.AcceptNumber(l, scan.GoNumber)
+	  if err != nil {
+	    return l.EmitErrorf("%s", err)
+	  }
+	  ...
+	}
+*/
+package scan
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/lex"
+)
+
+// Error describes a malformed literal. Line and Pos point to the location
+// where the problem was detected, or in the case of unterminated literals,
+// to the beginning of the literal
+type Error struct {
+	Line int
+	Pos  int
+	Msg  string
+}
+
+// Error returns the string representation of the error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at line %d", e.Msg, e.Line)
+}
+
+func errorf(l lex.Lexer, format string, args ...interface{}) *Error {
+	return &Error{
+		Line: l.Line(),
+		Pos:  l.Cursor(),
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+func isDigit(r rune, base int) bool {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r-'0') < base
+	case r >= 'a' && r <= 'f':
+		return base == 16
+	case r >= 'A' && r <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+func digitValue(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0')
+	case r >= 'a' && r <= 'f':
+		return int(r-'a') + 10
+	case r >= 'A' && r <= 'F':
+		return int(r-'A') + 10
+	}
+	return 16
+}
@@ -0,0 +1,347 @@
+package scan
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/lestrrat-go/lex"
+)
+
+func lexers(input string) map[string]lex.Lexer {
+	return map[string]lex.Lexer{
+		"string": lex.NewStringLexer(input, nil),
+		"reader": lex.NewReaderLexer(bytes.NewBufferString(input), nil),
+	}
+}
+
+func TestAcceptNumber(t *testing.T) {
+	tests := []struct {
+		spec   NumberSpec
+		input  string
+		kind   NumberKind
+		buffer string
+		err    string
+	}{
+		{GoNumber, "123 ", NumberInt, "123", ""},
+		{GoNumber, "1_000_000+", NumberInt, "1_000_000", ""},
+		{GoNumber, "0x_dead_BEEF", NumberInt, "0x_dead_BEEF", ""},
+		{GoNumber, "0b1011", NumberInt, "0b1011", ""},
+		{GoNumber, "0o777", NumberInt, "0o777", ""},
+		{GoNumber, "0777", NumberInt, "0777", ""},
+		{GoNumber, "089.5", NumberFloat, "089.5", ""},
+		{GoNumber, "1.5e-10", NumberFloat, "1.5e-10", ""},
+		{GoNumber, ".5", NumberFloat, ".5", ""},
+		{GoNumber, "1.", NumberFloat, "1.", ""},
+		{GoNumber, "2i", NumberInt, "2i", ""},
+		{GoNumber, "abc", NumberNone, "", ""},
+		{GoNumber, ".abc", NumberNone, "", ""},
+		{GoNumber, "089", NumberNone, "", "invalid digit '8' in octal literal at line 1"},
+		{GoNumber, "0x", NumberNone, "", "hexadecimal literal has no digits at line 1"},
+		{GoNumber, "0b102", NumberNone, "", "invalid digit '2' in binary literal at line 1"},
+		{GoNumber, "1__0", NumberNone, "", "'_' must separate successive digits at line 1"},
+		{GoNumber, "10_", NumberNone, "", "'_' must separate successive digits at line 1"},
+		{GoNumber, "1e+", NumberNone, "", "exponent has no digits at line 1"},
+		{CNumber, "42UL;", NumberInt, "42UL", ""},
+		{CNumber, "1.0f", NumberFloat, "1.0f", ""},
+		{JSONNumber, "-12.5e3,", NumberFloat, "-12.5e3", ""},
+		{JSONNumber, "0", NumberInt, "0", ""},
+		{JSONNumber, "01", NumberNone, "", "leading zeros are not permitted at line 1"},
+		{JSONNumber, "1.", NumberNone, "", "expected digit after '.' at line 1"},
+		{JSONNumber, "-x", NumberNone, "", "expected digit after '-' at line 1"},
+		{JSONNumber, "0x1", NumberInt, "0", ""},
+		{PythonNumber, "000", NumberInt, "000", ""},
+		{PythonNumber, "007.5j", NumberFloat, "007.5j", ""},
+		{PythonNumber, "010", NumberNone, "", "leading zeros in decimal integer literals are not permitted at line 1"},
+	}
+
+	for _, test := range tests {
+		for name, l := range lexers(test.input) {
+			kind, err := AcceptNumber(l, test.spec)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("%s: %q: expected error %q, got %v", name, test.input, test.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %q: unexpected error: %s", name, test.input, err)
+				continue
+			}
+			if kind != test.kind {
+				t.Errorf("%s: %q: expected kind %d, got %d", name, test.input, test.kind, kind)
+			}
+			if buf := l.BufferString(); buf != test.buffer {
+				t.Errorf("%s: %q: expected buffer %q, got %q", name, test.input, test.buffer, buf)
+			}
+		}
+	}
+}
+
+func TestAcceptQuoted(t *testing.T) {
+	tests := []struct {
+		spec   QuoteSpec
+		input  string
+		ok     bool
+		value  string
+		buffer string
+		err    string
+	}{
+		{GoString, `"hello\tworld" + x`, true, "hello\tworld", `"hello\tworld"`, ""},
+		{GoString, `"\x41\101é\U0001F600"`, true, "AAé\U0001F600", `"\x41\101é\U0001F600"`, ""},
+		{GoString, `"\xff"`, true, "\xff", `"\xff"`, ""},
+		{GoString, `hello`, false, "", "", ""},
+		{GoString, "\"foo\n\"", true, "", "", "newline in literal at line 1"},
+		{GoString, "\n\"foo\nbar\"", true, "", "", "newline in literal at line 2"},
+		{GoString, "\n\n\"foo\\q\"", true, "", "", `invalid escape \q at line 3`},
+		{GoString, `"\12"`, true, "", "", "invalid octal escape sequence at line 1"},
+		{GoString, `"\uD800"`, true, "", "", "escape sequence is invalid Unicode code point at line 1"},
+		{GoString, "\"foo", true, "", "", "unterminated string literal at line 1"},
+		{GoRune, `'\''`, true, "'", `'\''`, ""},
+		{GoRune, `''`, true, "", "", "empty character literal at line 1"},
+		{GoRune, `'ab'`, true, "", "", "more than one character in character literal at line 1"},
+		{CString, "\"a\\\nb\\x041\"", true, "abA", "\"a\\\nb\\x041\"", ""},
+		{JSONString, `"😀\/"`, true, "\U0001F600/", `"😀\/"`, ""},
+		{JSONString, `"\ud83dx"`, true, "�x", `"\ud83dx"`, ""},
+		{JSONString, `"\x41"`, true, "", "", `invalid escape \x at line 1`},
+		{PythonString, `'it\'s'`, true, "it's", `'it\'s'`, ""},
+	}
+
+	for _, test := range tests {
+		for name, l := range lexers(test.input) {
+			l.AcceptRun("\n")
+			value, ok, err := AcceptQuoted(l, test.spec)
+			if ok != test.ok {
+				t.Errorf("%s: %q: expected ok = %t, got %t", name, test.input, test.ok, ok)
+			}
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("%s: %q: expected error %q, got %v", name, test.input, test.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %q: unexpected error: %s", name, test.input, err)
+				continue
+			}
+			if value != test.value {
+				t.Errorf("%s: %q: expected value %q, got %q", name, test.input, test.value, value)
+			}
+			if buf := l.BufferString(); buf != test.buffer {
+				t.Errorf("%s: %q: expected buffer %q, got %q", name, test.input, test.buffer, buf)
+			}
+		}
+	}
+}
+
+func TestAcceptRawString(t *testing.T) {
+	tests := []struct {
+		delim  string
+		input  string
+		ok     bool
+		value  string
+		buffer string
+		err    string
+	}{
+		{"`", "`foo\\n\nbar` + x", true, "foo\\n\nbar", "`foo\\n\nbar`", ""},
+		{`"""`, `"""a""b"""`, true, `a""b`, `"""a""b"""`, ""},
+		{`"""`, `"""é""`, true, "", "", "unterminated raw string literal at line 1"},
+		{"`", "foo", false, "", "", ""},
+	}
+
+	for _, test := range tests {
+		for name, l := range lexers(test.input) {
+			value, ok, err := AcceptRawString(l, test.delim)
+			if ok != test.ok {
+				t.Errorf("%s: %q: expected ok = %t, got %t", name, test.input, test.ok, ok)
+			}
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("%s: %q: expected error %q, got %v", name, test.input, test.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %q: unexpected error: %s", name, test.input, err)
+				continue
+			}
+			if value != test.value {
+				t.Errorf("%s: %q: expected value %q, got %q", name, test.input, test.value, value)
+			}
+			if buf := l.BufferString(); buf != test.buffer {
+				t.Errorf("%s: %q: expected buffer %q, got %q", name, test.input, test.buffer, buf)
+			}
+		}
+	}
+}
+
+const (
+	itemComment = lex.ItemDefaultMax + 1 + iota
+	itemWord
+	itemSpace
+)
+
+func lexComments(l lex.Lexer) lex.LexFn {
+	switch {
+	case l.Peek() == lex.EOF:
+		l.Emit(lex.ItemEOF)
+		return nil
+	case AcceptLineComment(l, "//"):
+		l.Emit(itemComment)
+	case l.AcceptRun(" \n"):
+		l.Emit(itemSpace)
+	case l.AcceptRunExcept(" \n/("):
+		l.Emit(itemWord)
+	default:
+		ok, err := AcceptBlockComment(l, "(*", "*)", true)
+		if err != nil {
+			return l.EmitErrorf("%s", err)
+		}
+		if !ok {
+			return l.EmitErrorf("unexpected input")
+		}
+		l.Emit(itemComment)
+	}
+	return lexComments
+}
+
+func TestComments(t *testing.T) {
+	input := "a (* x\n(* y *)\n*) b // c\nd"
+	expected := []struct {
+		typ  lex.ItemType
+		line int
+		val  string
+	}{
+		{itemWord, 1, "a"},
+		{itemSpace, 1, " "},
+		{itemComment, 1, "(* x\n(* y *)\n*)"},
+		{itemSpace, 3, " "},
+		{itemWord, 3, "b"},
+		{itemSpace, 3, " "},
+		{itemComment, 3, "// c"},
+		{itemSpace, 3, "\n"},
+		{itemWord, 4, "d"},
+		{lex.ItemEOF, 4, ""},
+	}
+
+	for name, l := range map[string]lex.Lexer{
+		"string": lex.NewStringLexer(input, lexComments),
+		"reader": lex.NewReaderLexer(bytes.NewBufferString(input), lexComments),
+	} {
+		go l.Run()
+		i := 0
+		for item := range l.Items() {
+			if i >= len(expected) {
+				t.Fatalf("%s: unexpected item %s", name, item)
+			}
+			e := expected[i]
+			if item.Type() != e.typ || item.Line() != e.line || item.Value() != e.val {
+				t.Errorf("%s: expected %s at line %d (%q), got %s at line %d (%q)", name, e.typ, e.line, e.val, item.Type(), item.Line(), item.Value())
+			}
+			i++
+		}
+		if i != len(expected) {
+			t.Errorf("%s: expected %d items, got %d", name, len(expected), i)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	for name, l := range lexers("x\n/* foo\n/* bar */") {
+		l.AcceptRun("x\n")
+		ok, err := AcceptBlockComment(l, "/*", "*/", true)
+		if !ok {
+			t.Errorf("%s: expected comment to be found", name)
+		}
+		if err == nil || err.Error() != "unterminated comment at line 2" {
+			t.Errorf("%s: expected unterminated comment error, got %v", name, err)
+		}
+		if e, ok := err.(*Error); !ok || e.Pos != 2 {
+			t.Errorf("%s: expected error to point at position 2, got %#v", name, err)
+		}
+	}
+}
+
+func TestCharClass(t *testing.T) {
+	vowels := NewCharClass("aeiouäö")
+	digits := RangeClass('0', '9')
+	c := vowels.Union(digits)
+	for _, r := range "aeiouäö0123456789" {
+		if !c.Contains(r) {
+			t.Errorf("expected %q to be in the class", r)
+		}
+		if c.Negate().Contains(r) {
+			t.Errorf("expected %q not to be in the negated class", r)
+		}
+	}
+	for _, r := range "bxyzü_ " {
+		if c.Contains(r) {
+			t.Errorf("expected %q not to be in the class", r)
+		}
+		if !c.Negate().Contains(r) {
+			t.Errorf("expected %q to be in the negated class", r)
+		}
+	}
+	if c.Negate().Contains(lex.EOF) {
+		t.Errorf("expected EOF not to be in the negated class")
+	}
+}
+
+func TestAcceptIdentifier(t *testing.T) {
+	tests := []struct {
+		spec   IdentSpec
+		input  string
+		buffer string
+	}{
+		{UnicodeIdent, "_foo1 bar", "_foo1"},
+		{UnicodeIdent, "名前=1", "名前"},
+		{UnicodeIdent, "café·x+", "café·x"},
+		{UnicodeIdent, "1abc", ""},
+		{UnicodeIdent, "ͺ", ""},
+		{GoIdent, "αβγ_1.x", "αβγ_1"},
+		{ASCIIIdent, "abc_1é", "abc_1"},
+	}
+
+	for _, test := range tests {
+		for name, l := range lexers(test.input) {
+			ok := AcceptIdentifier(l, test.spec)
+			if ok != (test.buffer != "") {
+				t.Errorf("%s: %q: unexpected result %t", name, test.input, ok)
+			}
+			if buf := l.BufferString(); buf != test.buffer {
+				t.Errorf("%s: %q: expected buffer %q, got %q", name, test.input, test.buffer, buf)
+			}
+		}
+	}
+}
+
+func TestAcceptRunTable(t *testing.T) {
+	for name, l := range lexers("ΑΒΓabc123") {
+		if !AcceptRunTable(l, unicode.Greek, unicode.Latin) {
+			t.Errorf("%s: expected AcceptRunTable to succeed", name)
+		}
+		if buf := l.BufferString(); buf != "ΑΒΓabc" {
+			t.Errorf("%s: expected buffer %q, got %q", name, "ΑΒΓabc", buf)
+		}
+	}
+}
+
+const benchClassRunes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_$"
+
+var benchClassInput = strings.Repeat("xyzzy_42$", 10000)
+
+func BenchmarkAcceptRun(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		l := lex.NewStringLexer(benchClassInput, nil)
+		l.AcceptRun(benchClassRunes)
+	}
+}
+
+func BenchmarkAcceptRunClass(b *testing.B) {
+	c := NewCharClass(benchClassRunes)
+	for i := 0; i < b.N; i++ {
+		l := lex.NewStringLexer(benchClassInput, nil)
+		AcceptRunClass(l, c)
+	}
+}
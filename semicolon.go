@@ -0,0 +1,82 @@
+package lex
+
+import "strings"
+
+// SemicolonInserter wraps an ItemSource, and inserts a synthetic
+// terminator Item whenever a line ends right after one of the
+// "statement-ending" ItemTypes, similar to Go's automatic semicolon
+// insertion. A terminator is also inserted before ItemEOF if the last
+// item was a statement-ending one.
+//
+// A line is considered to have ended upon an ItemNewline, or upon an item
+// consisting only of whitespace which contains a newline. Other items that
+// consist only of whitespace are skipped over, as are items of the types
+// specified via SetTransparent (e.g. comments).
+//
+// The terminator is a zero-width Item, positioned at the item that ended
+// the line. Since it works on the item stream, it can be used with any
+// LexFn based grammar
+type SemicolonInserter struct {
+	src         ItemSource
+	terminator  ItemType
+	enders      map[ItemType]struct{}
+	transparent map[ItemType]struct{}
+	armed       bool
+	pending     LexItem
+}
+
+// NewSemicolonInserter creates a new SemicolonInserter, which reads from
+// `src` and inserts items of type `terminator` after any of `enders`
+func NewSemicolonInserter(src ItemSource, terminator ItemType, enders ...ItemType) *SemicolonInserter {
+	s := &SemicolonInserter{
+		src:         src,
+		terminator:  terminator,
+		enders:      make(map[ItemType]struct{}),
+		transparent: make(map[ItemType]struct{}),
+	}
+	for _, t := range enders {
+		s.enders[t] = struct{}{}
+	}
+	return s
+}
+
+// SetTransparent specifies ItemTypes that do not affect the insertion,
+// such as comments. If an item of one of these types contains a newline,
+// it ends the line
+func (s *SemicolonInserter) SetTransparent(types ...ItemType) {
+	for _, t := range types {
+		s.transparent[t] = struct{}{}
+	}
+}
+
+// NextItem returns the next Item, which may be a synthetic terminator
+func (s *SemicolonInserter) NextItem() LexItem {
+	if item := s.pending; item != nil {
+		s.pending = nil
+		return item
+	}
+
+	item := s.src.NextItem()
+	if item == nil {
+		return nil
+	}
+
+	_, transparent := s.transparent[item.Type()]
+	blank := strings.TrimSpace(item.Value()) == ""
+	switch {
+	case item.Type() == ItemEOF,
+		item.Type() == ItemNewline,
+		(transparent || blank) && strings.Contains(item.Value(), "\n"):
+		if s.armed {
+			s.armed = false
+			s.pending = item
+			terminator := NewItem(s.terminator, item.Pos(), item.Line(), "")
+			terminator.col = item.Column()
+			return terminator
+		}
+	case transparent, blank && item.Type() != ItemError:
+	default:
+		_, s.armed = s.enders[item.Type()]
+	}
+	return item
+}
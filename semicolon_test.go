@@ -0,0 +1,27 @@
+package lex
+
+import "testing"
+
+func TestSemicolonInserter(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer("1 +\n 2\n3", tlc.lexStart)
+	go l.Run()
+
+	const itemSemicolon = ItemDefaultMax + 100
+	s := NewSemicolonInserter(l, itemSemicolon, ItemNumber)
+	expected := []Item{
+		NewItem(ItemNumber, 0, 1, "1"),
+		NewItem(ItemWhitespace, 1, 1, " "),
+		NewItem(ItemOperator, 2, 1, "+"),
+		NewItem(ItemWhitespace, 3, 1, "\n"),
+		NewItem(ItemWhitespace, 4, 2, " "),
+		NewItem(ItemNumber, 5, 2, "2"),
+		NewItem(itemSemicolon, 6, 2, ""),
+		NewItem(ItemWhitespace, 6, 2, "\n"),
+		NewItem(ItemNumber, 7, 3, "3"),
+		NewItem(itemSemicolon, 8, 3, ""),
+		NewItem(ItemEOF, 8, 3, ""),
+	}
+
+	verifyItems(t, expected, collect(s))
+}
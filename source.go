@@ -0,0 +1,78 @@
+package lex
+
+import (
+	"io"
+	"os"
+)
+
+// Source describes the input to be lexed. Use StringSource, BytesSource,
+// ReaderSource or FileSource to create one, and pass it to New()
+type Source interface {
+	newLexer(LexFn, []Option) Lexer
+}
+
+type stringSource string
+type bytesSource []byte
+type readerSource struct {
+	io.Reader
+}
+type fileSource string
+
+// StringSource creates a Source that lexes the given string.
+// The resulting lexer is a *StringLexer
+func StringSource(s string) Source {
+	return stringSource(s)
+}
+
+// BytesSource creates a Source that lexes the given byte slice. The
+// contents are copied, so the slice may be modified after the lexer
+// has been created. The resulting lexer is a *StringLexer
+func BytesSource(b []byte) Source {
+	return bytesSource(b)
+}
+
+// ReaderSource creates a Source that lexes the contents of the given
+// io.Reader. The resulting lexer is a *ReaderLexer
+func ReaderSource(r io.Reader) Source {
+	return readerSource{r}
+}
+
+// FileSource creates a Source that lexes the contents of the file
+// in the given path. The file is opened when Run() is called, and closed
+// when it is done, so a lexer that is never run does not hold on to a
+// file descriptor. The filename is set to `path`, unless specified
+// otherwise via WithFilename.
+//
+// If the file could not be opened, the lexer emits a single Error Item
+// when run. The resulting lexer is a *ReaderLexer
+func FileSource(path string) Source {
+	return fileSource(path)
+}
+
+func (s stringSource) newLexer(fn LexFn, options []Option) Lexer {
+	return newStringLexer(string(s), fn, newConfig(options))
+}
+
+func (s bytesSource) newLexer(fn LexFn, options []Option) Lexer {
+	return newStringLexer(string(s), fn, newConfig(options))
+}
+
+func (s readerSource) newLexer(fn LexFn, options []Option) Lexer {
+	return newReaderLexer(s.Reader, fn, newConfig(options))
+}
+
+func (s fileSource) newLexer(fn LexFn, options []Option) Lexer {
+	c := newConfig(append([]Option{WithFilename(string(s))}, options...))
+
+	l := newReaderLexer(nil, fn, c)
+	l.open = func() (io.ReadCloser, error) {
+		return os.Open(string(s))
+	}
+	return l
+}
+
+// New creates a new Lexer that lexes the input from `src`, starting
+// with `fn`. The concrete type of the Lexer depends on the Source
+func New(src Source, fn LexFn, options ...Option) Lexer {
+	return src.newLexer(fn, options)
+}
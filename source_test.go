@@ -0,0 +1,61 @@
+package lex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lex-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "input.txt")
+	if err := ioutil.WriteFile(path, []byte("1 +\n 2"), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	tlc := &testLexCtx{}
+	sources := map[string]Source{
+		"string": StringSource("1 +\n 2"),
+		"bytes":  BytesSource([]byte("1 +\n 2")),
+		"reader": ReaderSource(bytes.NewBufferString("1 +\n 2")),
+		"file":   FileSource(path),
+	}
+	for name, src := range sources {
+		t.Logf("-----> %s", name)
+		l := New(src, tlc.lexStart)
+		go l.Run()
+		verify(t, l)
+	}
+
+	l := New(FileSource(path), tlc.lexStart).(*ReaderLexer)
+	if l.Filename() != path {
+		t.Errorf("expected filename to be %s, got %s", path, l.Filename())
+	}
+	go l.Run()
+	verify(t, l)
+}
+
+func TestNew_FileNotFound(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := New(FileSource("does-not-exist.txt"), tlc.lexStart)
+	go l.Run()
+
+	item := l.NextItem()
+	if item == nil || item.Type() != ItemError {
+		t.Fatalf("expected an error item, got %#v", item)
+	}
+	if strings.Count(item.Value(), "does-not-exist.txt") != 1 {
+		t.Errorf("expected the path to appear once in %q", item.Value())
+	}
+	if item := l.NextItem(); item != nil {
+		t.Errorf("expected no more items, got %#v", item)
+	}
+}
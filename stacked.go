@@ -0,0 +1,142 @@
+package lex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileItem is a LexItem annotated with the name of the input it was
+// lexed from. StackedLexer hands out FileItems
+type FileItem struct {
+	LexItem
+	filename string
+}
+
+// Filename returns the name of the input that the item was lexed from
+func (i FileItem) Filename() string {
+	return i.filename
+}
+
+// String returns the string representation of the FileItem
+func (i FileItem) String() string {
+	return fmt.Sprintf("%s: %s (%q)", i.filename, i.Type(), i.Value())
+}
+
+// StackedLexer splices the items from multiple inputs into a single item
+// stream, e.g. to implement include directives. A new input can be pushed
+// at any point, either by the consumer via Push, or from within a LexFn
+// via Include. The items from the new input are then handed out until its
+// EOF, after which the previous input is resumed. The ItemEOF of the
+// pushed inputs are not handed out.
+//
+// Each lexer is run in its own goroutine, which is started the first time
+// an item is requested from it. Do not call Run() on them yourself
+type StackedLexer struct {
+	stack   []*stackFrame
+	options []Option
+}
+
+type stackFrame struct {
+	name    string
+	lexer   Lexer
+	started bool
+}
+
+type includeRequest struct {
+	name string
+	src  Source
+	fn   LexFn
+}
+
+// NewStackedLexer creates a new StackedLexer, whose outermost input is
+// `src`. The options are applied to all lexers, including the ones that
+// are pushed later. The filename of each lexer is set to the name it was
+// pushed with
+func NewStackedLexer(name string, src Source, fn LexFn, options ...Option) *StackedLexer {
+	s := &StackedLexer{options: options}
+	s.push(name, src, fn)
+	return s
+}
+
+func (s *StackedLexer) push(name string, src Source, fn LexFn) {
+	options := append(append([]Option{}, s.options...), WithFilename(name))
+	s.stack = append(s.stack, &stackFrame{
+		name:  name,
+		lexer: New(src, fn, options...),
+	})
+}
+
+// Push starts lexing `src` using `fn`. The items from `src` are handed out
+// starting with the next call to NextItem. An error is returned if an
+// input with the same name is already being lexed, as that would cause
+// an endless include cycle. Inputs with an empty name are not checked
+func (s *StackedLexer) Push(name string, src Source, fn LexFn) error {
+	if name != "" {
+		for i, frame := range s.stack {
+			if frame.name != name {
+				continue
+			}
+
+			var chain []string
+			for _, f := range s.stack[i:] {
+				chain = append(chain, f.name)
+			}
+			chain = append(chain, name)
+			return fmt.Errorf("include cycle detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	s.push(name, src, fn)
+	return nil
+}
+
+// Include is meant to be called from within a LexFn running on any of the
+// lexers in the stack. It arranges for `src` to be pushed once the items
+// emitted by `l` so far have been handed out, so that its items appear
+// in the right place in the stream. If the push fails, an ItemError is
+// handed out in its place
+func (s *StackedLexer) Include(l Lexer, name string, src Source, fn LexFn) {
+	l.EmitItem(NewItemWithPayload(itemInclude, l.Cursor(), l.Line(), name, &includeRequest{
+		name: name,
+		src:  src,
+		fn:   fn,
+	}))
+}
+
+// Depth returns the number of inputs currently being lexed
+func (s *StackedLexer) Depth() int {
+	return len(s.stack)
+}
+
+// NextItem returns the next Item from the innermost input, as a FileItem.
+// Returns nil once the outermost input is done
+func (s *StackedLexer) NextItem() LexItem {
+	for len(s.stack) > 0 {
+		frame := s.stack[len(s.stack)-1]
+		if !frame.started {
+			frame.started = true
+			go frame.lexer.Run()
+		}
+
+		item := frame.lexer.NextItem()
+		switch {
+		case item == nil || (item.Type() == ItemEOF && len(s.stack) > 1):
+			if len(s.stack) == 1 {
+				return nil
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+		case item.Type() == itemInclude:
+			req, ok := item.Payload().(*includeRequest)
+			if !ok {
+				return FileItem{item, frame.name}
+			}
+			if err := s.Push(req.name, req.src, req.fn); err != nil {
+				errItem := NewItem(ItemError, item.Pos(), item.Line(), err.Error()).WithColumn(item.Column())
+				return FileItem{errItem, frame.name}
+			}
+		default:
+			return FileItem{item, frame.name}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package lex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackedLexer(t *testing.T) {
+	files := map[string]string{
+		"main.conf":  "a\ninclude inc.conf\nb",
+		"inc.conf":   "x\ninclude deep.conf\ny",
+		"deep.conf":  "z",
+		"cycle.conf": "include main.conf",
+	}
+
+	var s *StackedLexer
+	var lexConf LexFn
+	lexConf = func(l Lexer) LexFn {
+		switch {
+		case l.Peek() == EOF:
+			l.Emit(ItemEOF)
+			return nil
+		case l.AcceptRun(" \n"):
+			l.Emit(ItemWhitespace)
+		case l.AcceptString("include "):
+			l.Ignore()
+			l.AcceptRunExcept("\n")
+			name := l.BufferString()
+			l.Ignore()
+			s.Include(l, name, StringSource(files[name]), lexConf)
+		default:
+			l.AcceptRunExcept(" \n")
+			l.Emit(ItemNumber)
+		}
+		return lexConf
+	}
+
+	s = NewStackedLexer("main.conf", StringSource(files["main.conf"]), lexConf)
+	var got []string
+	for item := s.NextItem(); item != nil; item = s.NextItem() {
+		fi := item.(FileItem)
+		if item.Type() == ItemWhitespace {
+			continue
+		}
+		got = append(got, fi.Filename()+":"+item.Value())
+	}
+
+	expected := []string{
+		"main.conf:a",
+		"inc.conf:x",
+		"deep.conf:z",
+		"inc.conf:y",
+		"main.conf:b",
+		"main.conf:",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	s = NewStackedLexer("main.conf", StringSource("include cycle.conf"), lexConf)
+	var last LexItem
+	for item := s.NextItem(); item != nil; item = s.NextItem() {
+		if item.Type() == ItemError {
+			last = item
+		}
+	}
+	const msg = "include cycle detected: main.conf -> cycle.conf -> main.conf"
+	if last == nil || last.Value() != msg {
+		t.Errorf("expected error %q, got %#v", msg, last)
+	}
+	if fi, ok := last.(FileItem); !ok || fi.Filename() != "cycle.conf" {
+		t.Errorf("expected error to come from cycle.conf, got %#v", last)
+	}
+}
+
+func TestStackedLexer_IndentTracker(t *testing.T) {
+	s := NewStackedLexer("main", StringSource("a\n  b\nc"), lexIndentLine, WithContext(NewIndentTracker(4)))
+	expected := []Item{
+		NewItem(ItemNumber, 0, 1, "a"),
+		NewItem(ItemNewline, 1, 1, "\n"),
+		NewItem(ItemIndent, 2, 2, "  "),
+		NewItem(ItemNumber, 4, 2, "b"),
+		NewItem(ItemNewline, 5, 2, "\n"),
+		NewItem(ItemDedent, 6, 3, ""),
+		NewItem(ItemNumber, 6, 3, "c"),
+		NewItem(ItemEOF, 7, 3, ""),
+	}
+	verifyItems(t, expected, collect(s))
+}
+
+func TestStackedLexer_IncludeTypeWithoutRequest(t *testing.T) {
+	s := NewStackedLexer("main", StringSource(""), func(l Lexer) LexFn {
+		l.EmitValue(itemInclude, "not a request")
+		l.Emit(ItemEOF)
+		return nil
+	})
+	items := collect(s)
+	if len(items) != 2 || items[0].Type() != itemInclude || items[0].Value() != "not a request" {
+		t.Errorf("expected the item to be passed through, got %v", items)
+	}
+}
@@ -0,0 +1,135 @@
+package lex
+
+import "strings"
+
+// ItemSource is implemented by anything that hands out items one at a
+// time, such as a Lexer. NextItem returns nil once there are no more items
+type ItemSource interface {
+	NextItem() LexItem
+}
+
+// ItemSourceFunc is an adapter to allow the use of ordinary functions as
+// ItemSources
+type ItemSourceFunc func() LexItem
+
+// NextItem calls fn()
+func (fn ItemSourceFunc) NextItem() LexItem {
+	return fn()
+}
+
+// FromConsumer creates an ItemSource that reads items from a Consumer,
+// so that Consumers can be wrapped by the stream operators as well
+func FromConsumer(c Consumer) ItemSource {
+	return ItemSourceFunc(c.Consume)
+}
+
+func typeSet(types []ItemType) map[ItemType]struct{} {
+	set := make(map[ItemType]struct{}, len(types))
+	for _, t := range types {
+		set[t] = struct{}{}
+	}
+	return set
+}
+
+// Filter creates an ItemSource that only yields the items from `src`
+// whose type is one of `types`. EOF and Error items are always passed
+// through
+func Filter(src ItemSource, types ...ItemType) ItemSource {
+	set := typeSet(types)
+	set[ItemEOF] = struct{}{}
+	set[ItemError] = struct{}{}
+	return ItemSourceFunc(func() LexItem {
+		for {
+			item := src.NextItem()
+			if item == nil {
+				return nil
+			}
+			if _, ok := set[item.Type()]; ok {
+				return item
+			}
+		}
+	})
+}
+
+// Drop creates an ItemSource that yields the items from `src`, except
+// for those whose type is one of `types`, such as whitespace
+func Drop(src ItemSource, types ...ItemType) ItemSource {
+	set := typeSet(types)
+	return ItemSourceFunc(func() LexItem {
+		for {
+			item := src.NextItem()
+			if item == nil {
+				return nil
+			}
+			if _, ok := set[item.Type()]; !ok {
+				return item
+			}
+		}
+	})
+}
+
+// Map creates an ItemSource that yields the result of calling `fn` on
+// each item from `src`, e.g. to reclassify identifiers as keywords.
+// If `fn` returns nil, the item is dropped
+func Map(src ItemSource, fn func(LexItem) LexItem) ItemSource {
+	return ItemSourceFunc(func() LexItem {
+		for {
+			item := src.NextItem()
+			if item == nil {
+				return nil
+			}
+			if item = fn(item); item != nil {
+				return item
+			}
+		}
+	})
+}
+
+// Merge creates an ItemSource that merges adjacent items from `src` of
+// the same type into a single item, if their type is one of `types`.
+// The merged item has the position and line of the first item, and the
+// concatenated values. Note that if items in between have been dropped
+// beforehand, their values are not part of the merged item
+func Merge(src ItemSource, types ...ItemType) ItemSource {
+	return &merger{
+		src:   src,
+		types: typeSet(types),
+	}
+}
+
+type merger struct {
+	src     ItemSource
+	types   map[ItemType]struct{}
+	pending LexItem
+}
+
+func (m *merger) NextItem() LexItem {
+	item := m.pending
+	m.pending = nil
+	if item == nil {
+		item = m.src.NextItem()
+	}
+	if item == nil {
+		return nil
+	}
+	if _, ok := m.types[item.Type()]; !ok {
+		return item
+	}
+
+	var values []string
+	for {
+		next := m.src.NextItem()
+		if next == nil || next.Type() != item.Type() {
+			m.pending = next
+			break
+		}
+		values = append(values, next.Value())
+	}
+
+	if len(values) == 0 {
+		return item
+	}
+	merged := NewItem(item.Type(), item.Pos(), item.Line(), item.Value()+strings.Join(values, ""))
+	merged.col = item.Column()
+	return merged
+}
@@ -0,0 +1,88 @@
+package lex
+
+import "testing"
+
+func collect(src ItemSource) []LexItem {
+	var items []LexItem
+	for item := src.NextItem(); item != nil; item = src.NextItem() {
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestStreamOperators(t *testing.T) {
+	const input = "1 + 23  +\n 4"
+	tlc := &testLexCtx{}
+	run := func() Lexer {
+		l := NewStringLexer(input, tlc.lexStart)
+		go l.Run()
+		return l
+	}
+
+	const itemKeyword = ItemDefaultMax + 100
+	tests := map[string]struct {
+		src      ItemSource
+		expected []Item
+	}{
+		"Filter": {
+			Filter(run(), ItemNumber),
+			[]Item{
+				NewItem(ItemNumber, 0, 1, "1"),
+				NewItem(ItemNumber, 4, 1, "23"),
+				NewItem(ItemNumber, 11, 2, "4"),
+				NewItem(ItemEOF, 12, 2, ""),
+			},
+		},
+		"Drop+Merge": {
+			Merge(Drop(run(), ItemNumber), ItemWhitespace),
+			[]Item{
+				NewItem(ItemWhitespace, 1, 1, " "),
+				NewItem(ItemOperator, 2, 1, "+"),
+				NewItem(ItemWhitespace, 3, 1, "   "),
+				NewItem(ItemOperator, 8, 1, "+"),
+				NewItem(ItemWhitespace, 9, 1, "\n "),
+				NewItem(ItemEOF, 12, 2, ""),
+			},
+		},
+		"Map": {
+			Map(Drop(run(), ItemWhitespace), func(item LexItem) LexItem {
+				switch {
+				case item.Value() == "23":
+					return NewItem(itemKeyword, item.Pos(), item.Line(), item.Value())
+				case item.Type() == ItemOperator:
+					return nil
+				}
+				return item
+			}),
+			[]Item{
+				NewItem(ItemNumber, 0, 1, "1"),
+				NewItem(itemKeyword, 4, 1, "23"),
+				NewItem(ItemNumber, 11, 2, "4"),
+				NewItem(ItemEOF, 12, 2, ""),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			verifyItems(t, test.expected, collect(test.src))
+		})
+	}
+}
+
+func TestStreamOperators_Consumer(t *testing.T) {
+	tlc := &testLexCtx{}
+	l := NewStringLexer("1 + 2", tlc.lexStart)
+	go l.Run()
+
+	c := NewItemConsume(Drop(l, ItemWhitespace))
+	if item := c.Peek(); item.Value() != "1" {
+		t.Errorf("expected 1, got %#v", item)
+	}
+
+	items := collect(Filter(FromConsumer(c), ItemOperator))
+	if len(items) != 2 || items[0].Value() != "+" || items[1].Type() != ItemEOF {
+		t.Errorf("expected + and EOF, got %v", items)
+	}
+}
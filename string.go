@@ -2,6 +2,7 @@ package lex
 
 import (
 	"fmt"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -13,26 +14,62 @@ type StringLexer struct {
 	start       int
 	pos         int
 	line        int
+	startLine   int
+	col         int
+	startCol    int
+	nlPos       int
+	nlCol       int
 	width       int
-	items       chan LexItem
+	eofs        int
+	out         output
 	entryPoint  LexFn
+	filename    string
+	context     interface{}
 }
 
 // NewStringLexer creates a new StringLexer instance. This lexer can be
-// used only once per input string. Do not try to reuse it
-func NewStringLexer(input string, fn LexFn) *StringLexer {
+// used only once per input string. Do not try to reuse it.
+// This is equivalent to New(StringSource(input), fn, options...)
+func NewStringLexer(input string, fn LexFn, options ...Option) *StringLexer {
+	return New(StringSource(input), fn, options...).(*StringLexer)
+}
+
+func newStringLexer(input string, fn LexFn, c *config) *StringLexer {
 	return &StringLexer{
 		input:       input,
 		inputLength: len(input),
 		start:       0,
 		pos:         0,
 		line:        1,
+		startLine:   1,
+		col:         1,
+		startCol:    1,
+		nlPos:       -1,
 		width:       0,
-		items:       make(chan LexItem, 1),
+		out:         newOutput(c),
 		entryPoint:  fn,
+		filename:    c.filename,
+		context:     c.context,
 	}
 }
 
+// Filename returns the name of the input, as specified by WithFilename
+func (l *StringLexer) Filename() string {
+	return l.filename
+}
+
+// SetContext associates an arbitrary value with the lexer, so that
+// LexFn functions can keep their state (e.g. nesting depth, configuration)
+// without having to be bound to an object
+func (l *StringLexer) SetContext(v interface{}) {
+	l.context = v
+}
+
+// Context returns the value associated with SetContext or WithContext
+func (l *StringLexer) Context() interface{} {
+	return l.context
+}
+
 // GetEntryPoint returns the function that lexing is started with
 func (l *StringLexer) GetEntryPoint() LexFn {
 	return l.entryPoint
@@ -42,8 +79,12 @@ func (l *StringLexer) inputLen() int {
 	return l.inputLength
 }
 
-// Current returns the current rune being considered
+// Current returns the rune at the cursor, which is the rune that the
+// next call to Next returns. The cursor is not moved
 func (l *StringLexer) Current() (r rune) {
+	if l.pos >= l.inputLen() {
+		return EOF
+	}
 	r, _ = utf8.DecodeRuneInString(l.input[l.pos:])
 	return r
 }
@@ -52,6 +93,7 @@ func (l *StringLexer) Current() (r rune) {
 func (l *StringLexer) Next() (r rune) {
 	if l.pos >= l.inputLen() {
 		l.width = 0
+		l.eofs++
 		return EOF
 	}
 
@@ -64,9 +106,22 @@ func (l *StringLexer) Next() (r rune) {
 
 	r, l.width = utf8.DecodeRuneInString(l.input[l.pos:])
 	l.pos += l.width
+	l.advanceColumn(r)
 	return r
 }
 
+// advanceColumn updates the column after the cursor moved past `r`. The
+// column before the last newline is remembered, so that backing up over
+// it (e.g. after peeking at the end of a line) doesn't rescan the line
+func (l *StringLexer) advanceColumn(r rune) {
+	if r == '\n' {
+		l.nlPos, l.nlCol = l.pos-1, l.col
+		l.col = 1
+		return
+	}
+	l.col++
+}
+
 // Peek returns the next rune, but does not move the position
 func (l *StringLexer) Peek() (r rune) {
 	r = l.Next()
@@ -74,14 +129,39 @@ func (l *StringLexer) Peek() (r rune) {
 	return r
 }
 
-// Backup moves the cursor position (as many bytes as the last read rune)
+// Backup moves the cursor position back by one rune. It may be called
+// repeatedly to undo multiple calls to Next(), but never moves the cursor
+// before the end of the last item
 func (l *StringLexer) Backup() {
-	l.pos -= l.width
-	if l.width == 1 && l.pos >= 0 && l.inputLen() > l.pos {
-		if l.input[l.pos] == '\n' {
-			l.line--
-		}
+	// Reading EOF does not move the cursor, so backing up from it
+	// shouldn't either
+	if l.eofs > 0 {
+		l.eofs--
+		return
 	}
+
+	if l.pos <= l.start {
+		return
+	}
+
+	r, width := utf8.DecodeLastRuneInString(l.input[:l.pos])
+	l.pos -= width
+	if r != '\n' {
+		l.col--
+		return
+	}
+
+	l.line--
+	if l.pos == l.nlPos {
+		l.col = l.nlCol
+	} else {
+		l.col = l.column(l.pos)
+	}
+}
+
+// Line returns the line number of the current cursor position
+func (l *StringLexer) Line() int {
+	return l.line
 }
 
 // AcceptString returns true if the given string can be matched exactly.
@@ -124,29 +204,93 @@ func (l *StringLexer) AcceptRunExcept(valid string) bool {
 	return AcceptRunExcept(l, valid)
 }
 
+// AcceptAnySet moves the cursor 1 rune if the rune is contained in the
+// given set
+func (l *StringLexer) AcceptAnySet(set *RuneSet) bool {
+	return AcceptAnySet(l, set)
+}
+
+// AcceptRunSet moves the cursor forward as long as the input matches one
+// of the runes in the given set. The input string is scanned directly,
+// without going through Next()
+func (l *StringLexer) AcceptRunSet(set *RuneSet) bool {
+	start := l.pos
+	for l.pos < l.inputLength {
+		r, width := rune(l.input[l.pos]), 1
+		if r >= utf8.RuneSelf {
+			r, width = utf8.DecodeRuneInString(l.input[l.pos:])
+		}
+		if !set.Contains(r) {
+			break
+		}
+		if r == '\n' {
+			l.line++
+		}
+		l.pos += width
+		l.width = width
+		l.advanceColumn(r)
+	}
+	return l.pos > start
+}
+
 // EmitErrorf emits an Error Item
 func (l *StringLexer) EmitErrorf(format string, args ...interface{}) LexFn {
-	l.items <- NewItem(ItemError, l.pos, l.line, fmt.Sprintf(format, args...))
+	item := NewItem(ItemError, l.pos, l.line, fmt.Sprintf(format, args...))
+	item.col = l.col
+	l.out.send(item)
 	return nil
 }
 
 // Grab creates a new Item of type `t`. The value in the item is created
 // from the position of the last read item to current cursor position
 func (l *StringLexer) Grab(t ItemType) Item {
-	// special case
-	str := l.BufferString()
-	line := l.line
-	if len(str) > 0 && str[0] == '\n' {
-		line--
-	}
-	return NewItem(t, l.start, line, str)
+	item := NewItem(t, l.start, l.startLine, l.BufferString())
+	item.col = l.startCol
+	return item
 }
 
 // Emit creates and sends a new Item of type `t` through the output
 // channel. The Item is generated using `Grab`
 func (l *StringLexer) Emit(t ItemType) {
-	l.items <- l.Grab(t)
+	l.out.send(l.Grab(t))
+	l.start = l.pos
+	l.startLine = l.line
+	l.startCol = l.col
+}
+
+// EmitPayload works like Emit, but also associates a decoded value
+// (e.g. an int64 for a numeric literal, or the unescaped contents of a
+// string literal) with the Item, which is available via Payload()
+func (l *StringLexer) EmitPayload(t ItemType, v interface{}) {
+	item := l.Grab(t)
+	item.payload = v
+	l.out.send(item)
+	l.start = l.pos
+	l.startLine = l.line
+	l.startCol = l.col
+}
+
+// EmitValue emits a synthetic Item of type `t` with the given value,
+// positioned at the current cursor. Unlike Emit, the buffer is left
+// untouched, so this can be used to emit virtual tokens (e.g. automatic
+// semicolons) in the middle of lexing something else
+func (l *StringLexer) EmitValue(t ItemType, v string) {
+	item := NewItem(t, l.pos, l.line, v)
+	item.col = l.col
+	l.out.send(item)
+}
+
+// EmitItem sends the given Item through the output channel as is.
+// The buffer is left untouched
+func (l *StringLexer) EmitItem(item LexItem) {
+	l.out.send(item)
+}
+
+// Ignore discards the contents of the buffer, without emitting an Item
+func (l *StringLexer) Ignore() {
 	l.start = l.pos
+	l.startLine = l.line
+	l.startCol = l.col
 }
 
 // PrevByte returns the previous byte (l.Cursor - 1)
@@ -159,14 +303,57 @@ func (l *StringLexer) Cursor() int {
 	return l.pos
 }
 
+// Column returns the column of the current cursor position, counted in
+// runes starting at 1
+func (l *StringLexer) Column() int {
+	return l.col
+}
+
+// column computes the column of `pos` by scanning back to the beginning
+// of its line. Only used when the column can't be tracked incrementally
+func (l *StringLexer) column(pos int) int {
+	if pos > l.inputLen() {
+		pos = l.inputLen()
+	}
+	lineStart := strings.LastIndexByte(l.input[:pos], '\n') + 1
+	return utf8.RuneCountInString(l.input[lineStart:pos]) + 1
+}
+
 // LastCursor returns the end position of the last Grab
 func (l *StringLexer) LastCursor() int {
 	return l.start
 }
 
-// AdvanceCursor advances the cursor position by `n`
+// AdvanceCursor advances the cursor position by `n` bytes, and updates
+// the line number accordingly
 func (l *StringLexer) AdvanceCursor(n int) {
+	lo, hi := l.pos, l.pos+n
+	if n < 0 {
+		lo, hi = hi, lo
+	}
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > l.inputLen() {
+		hi = l.inputLen()
+	}
+
+	lines := 0
+	if lo < hi {
+		lines = strings.Count(l.input[lo:hi], "\n")
+		if n < 0 {
+			l.line -= lines
+		} else {
+			l.line += lines
+		}
+	}
 	l.pos += n
+
+	if n > 0 && lines == 0 && lo < hi {
+		l.col += utf8.RuneCountInString(l.input[lo:hi])
+	} else {
+		l.col = l.column(l.pos)
+	}
 }
 
 // BufferString reutrns the string beween LastCursor and Cursor
@@ -179,15 +366,43 @@ func (l *StringLexer) RemainingString() string {
 	return l.input[l.pos:]
 }
 
-// Items returns the channel where lex'ed Item structs are sent to
+// SetBatchSize turns on batched delivery: items are sent through the
+// channel returned by Batches() in chunks of up to `n` items, instead of
+// one by one through Items(). Pending items are flushed when an EOF or
+// an Error item is emitted, and when lexing ends. A value less than 2
+// turns batching off. This must be called before Run()
+func (l *StringLexer) SetBatchSize(n int) {
+	l.out.setBatchSize(n)
+}
+
+// Items returns the channel where lex'ed Item structs are sent to.
+// If batching is enabled, no items are sent through this channel.
+// Use NextItem() or Batches() instead
 func (l *StringLexer) Items() chan LexItem {
-	return l.items
+	return l.out.items
+}
+
+// Err returns the error returned by the item sink specified via
+// WithItemSink, if any
+func (l *StringLexer) Err() error {
+	return l.out.err
+}
+
+// Batches returns the channel where batches of lex'ed Item structs are
+// sent to. It is nil unless batching has been enabled via SetBatchSize()
+func (l *StringLexer) Batches() chan []LexItem {
+	return l.out.batches
 }
 
 // NextItem returns the next Item in the processing pipeline.
-// This is just a convenience function over reading l.Items()
+// This is just a convenience function over reading l.Items(), or
+// l.Batches() if batching is enabled
 func (l *StringLexer) NextItem() LexItem {
-	return <-l.items
+	return l.out.next()
+}
+
+func (l *StringLexer) output() *output {
+	return &l.out
 }
 
 // Run starts the lexing. You should be calling this method as a goroutine: